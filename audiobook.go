@@ -0,0 +1,206 @@
+package metadata
+
+import (
+	"path"
+	"strings"
+)
+
+// Track is an audio resource of the publication reading order.
+type Track struct {
+	ID       string   `yaml:"id,omitempty"` // manifest item id, required for EPUB duration
+	Href     string   `yaml:"href"`
+	Type     string   `yaml:"type,omitempty"` // media type
+	Title    string   `yaml:"title,omitempty"`
+	Duration Duration `yaml:"duration,omitempty"`
+}
+
+// AudioTypes maps audio file extensions to media types.
+var AudioTypes = map[string]string{
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".m4b":  "audio/mp4",
+	".mp4":  "audio/mp4",
+	".aac":  "audio/aac",
+	".ogg":  "audio/ogg",
+	".oga":  "audio/ogg",
+	".opus": "audio/opus",
+	".flac": "audio/flac",
+	".wav":  "audio/wav",
+	".webm": "audio/webm",
+}
+
+// mediaType return track media type defined or guessed by file extension.
+func (t Track) mediaType() string {
+	if t.Type != "" {
+		return t.Type
+	}
+	return AudioTypes[strings.ToLower(path.Ext(t.Href))]
+}
+
+// AudiobookManifest is a W3C Audiobooks publication manifest.
+//
+// https://www.w3.org/TR/audiobooks/
+type AudiobookManifest struct {
+	Context         []string            `json:"@context"`
+	ConformsTo      string              `json:"conformsTo"`
+	Type            string              `json:"type"`
+	ID              string              `json:"id,omitempty"`
+	Name            []AudiobookString   `json:"name"`
+	Author          []AudiobookPerson   `json:"author,omitempty"`
+	ReadBy          []AudiobookPerson   `json:"readBy,omitempty"`
+	Editor          []AudiobookPerson   `json:"editor,omitempty"`
+	Translator      []AudiobookPerson   `json:"translator,omitempty"`
+	Illustrator     []AudiobookPerson   `json:"illustrator,omitempty"`
+	Contributor     []AudiobookPerson   `json:"contributor,omitempty"`
+	Publisher       []AudiobookPerson   `json:"publisher,omitempty"`
+	InLanguage      string              `json:"inLanguage,omitempty"`
+	DatePublished   string              `json:"datePublished,omitempty"`
+	DateModified    string              `json:"dateModified,omitempty"`
+	Description     string              `json:"description,omitempty"`
+	Duration        Duration            `json:"duration,omitempty"`
+	Abridged        bool                `json:"abridged,omitempty"`
+	License         string              `json:"license,omitempty"`
+	CopyrightHolder *AudiobookPerson    `json:"copyrightHolder,omitempty"`
+	CopyrightYear   int                 `json:"copyrightYear,omitempty"`
+	ReadingOrder    []AudiobookResource `json:"readingOrder"`
+	Resources       []AudiobookResource `json:"resources,omitempty"`
+}
+
+// AudiobookString is a localizable string of publication manifest.
+type AudiobookString struct {
+	Value    string `json:"value"`
+	Language string `json:"language,omitempty"`
+}
+
+// AudiobookPerson is a creator of publication manifest.
+type AudiobookPerson struct {
+	Type string `json:"type"` // Person or Organization
+	Name string `json:"name"`
+}
+
+// AudiobookResource is a linked resource of publication manifest.
+type AudiobookResource struct {
+	Type           string   `json:"type"`
+	URL            string   `json:"url"`
+	Rel            []string `json:"rel,omitempty"`
+	EncodingFormat string   `json:"encodingFormat,omitempty"`
+	Name           string   `json:"name,omitempty"`
+	Duration       Duration `json:"duration,omitempty"`
+}
+
+// audiobookRoles is a MARC relator codes to publication manifest creator
+// properties.
+var audiobookRoles = map[string]string{
+	"aut": "author",
+	"nrt": "readBy",
+	"edt": "editor",
+	"trl": "translator",
+	"ill": "illustrator",
+	"pbl": "publisher",
+}
+
+// Audiobook return publication metadata as W3C Audiobooks manifest.
+func (p Publication) Audiobook() AudiobookManifest {
+	var manifest = AudiobookManifest{
+		Context:       []string{"https://schema.org", "https://www.w3.org/ns/pub-context"},
+		ConformsTo:    "https://www.w3.org/TR/audiobooks/",
+		Type:          "Audiobook",
+		InLanguage:    p.Language,
+		DatePublished: string(p.Date),
+		DateModified:  string(p.Modified),
+		Description:   p.DescriptionText(),
+		Duration:      p.Duration,
+		Abridged:      p.Abridged,
+		ReadingOrder:  []AudiobookResource{},
+	}
+
+	if id, ok := p.Identifier.primary(); ok {
+		manifest.ID = id.URN()
+	}
+
+	var rights = p.Licensing()
+	manifest.License = p.license().LicenseURL()
+	manifest.CopyrightYear = rights.Year
+	if rights.Holder != "" {
+		manifest.CopyrightHolder = &AudiobookPerson{Type: "Organization", Name: rights.Holder}
+		if p.Creator.has(rights.Holder) {
+			manifest.CopyrightHolder.Type = "Person"
+		}
+	}
+
+	for _, title := range p.Title {
+		if title.Type != "" && title.Type != "main" {
+			continue
+		}
+		manifest.Name = append(manifest.Name,
+			AudiobookString{Value: title.Text, Language: title.Lang})
+	}
+
+	// creators by role
+	addPerson := func(author Author, defaultRole string) {
+		var roles = author.Relators(p.Language)
+		if len(roles) == 0 {
+			roles = []string{defaultRole}
+		}
+		var added = make(map[string]bool)
+		for _, code := range roles {
+			var role = audiobookRoles[code]
+			if role == "" {
+				role = "contributor"
+			}
+			if added[role] {
+				continue
+			}
+			added[role] = true
+			var person = AudiobookPerson{Type: "Person", Name: author.Text}
+			switch role {
+			case "author":
+				manifest.Author = append(manifest.Author, person)
+			case "readBy":
+				manifest.ReadBy = append(manifest.ReadBy, person)
+			case "editor":
+				manifest.Editor = append(manifest.Editor, person)
+			case "translator":
+				manifest.Translator = append(manifest.Translator, person)
+			case "illustrator":
+				manifest.Illustrator = append(manifest.Illustrator, person)
+			case "publisher":
+				person.Type = "Organization"
+				manifest.Publisher = append(manifest.Publisher, person)
+			default:
+				manifest.Contributor = append(manifest.Contributor, person)
+			}
+		}
+	}
+	for _, author := range p.Creator.Sorted() {
+		addPerson(author, "aut")
+	}
+	for _, author := range p.Contributor.Sorted() {
+		addPerson(author, "")
+	}
+	if p.Publisher != "" && len(manifest.Publisher) == 0 {
+		manifest.Publisher = []AudiobookPerson{{Type: "Organization", Name: p.Publisher}}
+	}
+
+	for _, track := range p.ReadingOrder {
+		manifest.ReadingOrder = append(manifest.ReadingOrder, AudiobookResource{
+			Type:           "LinkedResource",
+			URL:            track.Href,
+			EncodingFormat: track.mediaType(),
+			Name:           track.Title,
+			Duration:       track.Duration,
+		})
+	}
+
+	if p.CoverImage != nil {
+		manifest.Resources = append(manifest.Resources, AudiobookResource{
+			Type:           "LinkedResource",
+			URL:            p.CoverImage.Path,
+			Rel:            []string{"cover"},
+			EncodingFormat: p.CoverImage.Type(),
+			Name:           p.CoverImage.Alt,
+		})
+	}
+
+	return manifest
+}
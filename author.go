@@ -2,16 +2,21 @@ package metadata
 
 import (
 	"fmt"
-	"strings"
+	"sort"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Author of publication.
+//
+// Role may be a single string or a list when one person has several roles.
+// DisplaySeq define the display order of authors independent of the YAML
+// order.
 type Author struct {
-	Role   string `yaml:"role,omitempty"`
-	Text   string `yaml:"text"`
-	FileAs string `yaml:"file-as,omitempty"`
+	Role       Strings `yaml:"role,omitempty,flow"`
+	Text       string  `yaml:"text"`
+	FileAs     string  `yaml:"file-as,omitempty"`
+	DisplaySeq int     `yaml:"display-seq,omitempty"`
 }
 
 // UnmarshalYAML implement yaml.Unmarshaler interface.
@@ -30,9 +35,56 @@ func (author *Author) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
-// MarkCode return MARC Code string for Author Role.
+// MARC return MARC Code string for the first Author Role.
 func (author Author) MARC() string {
-	return MARCCodes[strings.ToLower(author.Role)]
+	if codes := author.MARCs(); len(codes) > 0 {
+		return codes[0]
+	}
+	return ""
+}
+
+// MARCs return the list of MARC Codes for all Author Roles. Unknown roles
+// are skipped.
+func (author Author) MARCs() []string {
+	return author.Relators("")
+}
+
+// Relators return the list of MARC Codes for all Author Roles. Roles may be
+// MARC codes, english labels or labels localized for lang.
+func (author Author) Relators(lang string) []string {
+	var codes []string
+	for _, role := range author.Role {
+		code, ok := LookupRelator(role, lang)
+		if !ok {
+			continue
+		}
+		var dup bool
+		for _, c := range codes {
+			if c == code {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// Onix return Onix CodeList 17 contributor role for the first Author Role.
+func (author Author) Onix() string {
+	return OnixRole(author.MARC())
+}
+
+// isSimple return true if author can be written as a name only.
+func (author Author) isSimple() bool {
+	return len(author.Role) == 0 && author.FileAs == "" && author.DisplaySeq == 0
+}
+
+// hasRefinements return true if author requires EPUB refinements.
+func (author Author) hasRefinements() bool {
+	return len(author.MARCs()) > 0 || author.FileAs != "" || author.DisplaySeq > 0
 }
 
 // Authors is a list of Author.
@@ -66,14 +118,14 @@ func (authors Authors) MarshalYAML() (interface{}, error) {
 		}, nil
 	case 1:
 		var author = authors[0]
-		if author.Role == "" && author.FileAs == "" {
+		if author.isSimple() {
 			return author.Text, nil
 		}
 		return author, nil
 	default:
 		var list = make([]string, len(authors))
 		for i, author := range authors {
-			if author.Role != "" || author.FileAs != "" {
+			if !author.isSimple() {
 				return ([]Author)(authors), nil
 			}
 			list[i] = author.Text
@@ -81,3 +133,22 @@ func (authors Authors) MarshalYAML() (interface{}, error) {
 		return list, nil
 	}
 }
+
+// Sorted return a copy of authors ordered by DisplaySeq. Authors without
+// DisplaySeq follow the ordered ones and keep their original order.
+func (authors Authors) Sorted() Authors {
+	var list = make(Authors, len(authors))
+	copy(list, authors)
+	sort.SliceStable(list, func(i, j int) bool {
+		si, sj := list[i].DisplaySeq, list[j].DisplaySeq
+		switch {
+		case si == 0:
+			return false
+		case sj == 0:
+			return true
+		default:
+			return si < sj
+		}
+	})
+	return list
+}
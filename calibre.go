@@ -0,0 +1,285 @@
+package metadata
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CalibrePackage is a Calibre metadata.opf sidecar file.
+type CalibrePackage struct {
+	XMLName          xml.Name       `xml:"http://www.idpf.org/2007/opf package"`
+	Version          string         `xml:"version,attr"`
+	UniqueIdentifier string         `xml:"unique-identifier,attr"`
+	Metadata         OPFMetadata    `xml:"metadata"`
+	Guide            []OPFReference `xml:"guide>reference,omitempty"`
+}
+
+// OPFReference is an EPUB 2 guide reference.
+type OPFReference struct {
+	Type  string `xml:"type,attr"`
+	Title string `xml:"title,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+}
+
+// Calibre return publication metadata as Calibre metadata.opf package.
+//
+// Properties with "calibre:" prefix are written as Calibre metas:
+// calibre:rating, calibre:author_link_map, calibre:timestamp and custom
+// columns as calibre:user_metadata:#name. Identifier with "calibre" scheme
+// is a Calibre book id.
+func (p Publication) Calibre() *CalibrePackage {
+	var pkg = &CalibrePackage{
+		Version:  "2.0",
+		Metadata: p.EPUB2(),
+	}
+
+	// unique identifier: primary, uuid or the first one
+	if i := p.Identifier.Primary(); i >= 0 {
+		pkg.UniqueIdentifier = pkg.Metadata.Identifier[i].ID
+	}
+
+	// title sort
+	for _, title := range p.Title {
+		if title.Type == "main" && title.FileAs != "" {
+			pkg.Metadata.Meta = append(pkg.Metadata.Meta, OPFMeta{
+				Name: "calibre:title_sort", Content: title.FileAs})
+			break
+		}
+	}
+
+	// calibre properties
+	var names = make([]string, 0, len(p.Properties))
+	for name := range p.Properties {
+		if strings.HasPrefix(name, "calibre:") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var content string
+		switch value := p.Properties[name].(type) {
+		case string:
+			content = value
+		case int, int64, float64, bool:
+			content = fmt.Sprint(value)
+		default:
+			data, err := json.Marshal(jsonValue(value))
+			if err != nil {
+				continue
+			}
+			content = string(data)
+		}
+		pkg.Metadata.Meta = append(pkg.Metadata.Meta, OPFMeta{Name: name, Content: content})
+	}
+
+	if p.CoverImage != nil {
+		pkg.Guide = []OPFReference{{Type: "cover", Title: "Cover", Href: p.CoverImage.Path}}
+	}
+
+	return pkg
+}
+
+// jsonValue convert YAML decoded maps with interface keys to JSON
+// compatible form.
+func jsonValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		var m = make(map[string]interface{}, len(value))
+		for k, v := range value {
+			m[fmt.Sprint(k)] = jsonValue(v)
+		}
+		return m
+	case map[string]interface{}:
+		var m = make(map[string]interface{}, len(value))
+		for k, v := range value {
+			m[k] = jsonValue(v)
+		}
+		return m
+	case []interface{}:
+		var list = make([]interface{}, len(value))
+		for i, v := range value {
+			list[i] = jsonValue(v)
+		}
+		return list
+	default:
+		return value
+	}
+}
+
+// calibreOPF is a namespace aware OPF structure for reading.
+type calibreOPF struct {
+	Metadata struct {
+		Identifier  []calibreElement `xml:"http://purl.org/dc/elements/1.1/ identifier"`
+		Title       []calibreElement `xml:"http://purl.org/dc/elements/1.1/ title"`
+		Creator     []calibreElement `xml:"http://purl.org/dc/elements/1.1/ creator"`
+		Contributor []calibreElement `xml:"http://purl.org/dc/elements/1.1/ contributor"`
+		Date        []calibreElement `xml:"http://purl.org/dc/elements/1.1/ date"`
+		Description []calibreElement `xml:"http://purl.org/dc/elements/1.1/ description"`
+		Publisher   []calibreElement `xml:"http://purl.org/dc/elements/1.1/ publisher"`
+		Language    []calibreElement `xml:"http://purl.org/dc/elements/1.1/ language"`
+		Subject     []calibreElement `xml:"http://purl.org/dc/elements/1.1/ subject"`
+		Rights      []calibreElement `xml:"http://purl.org/dc/elements/1.1/ rights"`
+		Meta        []struct {
+			Name    string `xml:"name,attr"`
+			Content string `xml:"content,attr"`
+		} `xml:"meta"`
+	} `xml:"metadata"`
+	Guide []OPFReference `xml:"guide>reference"`
+}
+
+type calibreElement struct {
+	Role   string `xml:"http://www.idpf.org/2007/opf role,attr"`
+	FileAs string `xml:"http://www.idpf.org/2007/opf file-as,attr"`
+	Scheme string `xml:"http://www.idpf.org/2007/opf scheme,attr"`
+	Event  string `xml:"http://www.idpf.org/2007/opf event,attr"`
+	Value  string `xml:",chardata"`
+}
+
+// author return author from creator or contributor element.
+func (el calibreElement) author() Author {
+	var author = Author{Text: strings.TrimSpace(el.Value), FileAs: el.FileAs}
+	if el.Role != "" {
+		author.Role = Strings{el.Role}
+	}
+	return author
+}
+
+// ParseCalibre return publication metadata from Calibre metadata.opf.
+func ParseCalibre(data []byte) (*Publication, error) {
+	var opf calibreOPF
+	if err := xml.Unmarshal(data, &opf); err != nil {
+		return nil, err
+	}
+
+	var pub = new(Publication)
+	var md = opf.Metadata
+	for _, el := range md.Identifier {
+		var id = Identifier{Text: strings.TrimSpace(el.Value)}
+		switch scheme := strings.ToLower(el.Scheme); scheme {
+		case "isbn":
+			id.Scheme = "ISBN-13"
+			if len(strings.ReplaceAll(id.Text, "-", "")) == 10 {
+				id.Scheme = "ISBN-10"
+			}
+		case "uuid":
+			id.Scheme = "UUID"
+		case "doi":
+			id.Scheme = "DOI"
+		case "calibre":
+			id.Scheme = "calibre"
+		case "":
+			id.detectScheme()
+		default:
+			id.Scheme = el.Scheme
+		}
+		pub.Identifier = append(pub.Identifier, id)
+	}
+	for _, el := range md.Title {
+		pub.Title = append(pub.Title, Title{Type: "main", Text: strings.TrimSpace(el.Value)})
+	}
+	for _, el := range md.Creator {
+		pub.Creator = append(pub.Creator, el.author())
+	}
+	for _, el := range md.Contributor {
+		if el.FileAs == "calibre" {
+			continue // book producer
+		}
+		pub.Contributor = append(pub.Contributor, el.author())
+	}
+	for _, el := range md.Date {
+		if el.Event != "" && el.Event != "publication" {
+			continue
+		}
+		if date := calibreDate(el.Value); date != "" {
+			pub.Date = date
+		}
+	}
+	if len(md.Description) > 0 {
+		pub.Description = strings.TrimSpace(md.Description[0].Value)
+	}
+	if len(md.Publisher) > 0 {
+		pub.Publisher = strings.TrimSpace(md.Publisher[0].Value)
+	}
+	if len(md.Language) > 0 {
+		pub.Language = isoLanguage(strings.TrimSpace(md.Language[0].Value))
+	}
+	if len(md.Rights) > 0 {
+		pub.Rights = strings.TrimSpace(md.Rights[0].Value)
+	}
+	for _, el := range md.Subject {
+		pub.Subject = append(pub.Subject, strings.TrimSpace(el.Value))
+	}
+
+	for _, meta := range md.Meta {
+		switch name := meta.Name; {
+		case name == "calibre:series":
+			pub.BelongsToCollection = meta.Content
+		case name == "calibre:series_index":
+			pub.GroupPosition = meta.Content
+		case name == "calibre:title_sort":
+			if len(pub.Title) > 0 {
+				pub.Title[0].FileAs = meta.Content
+			}
+		case name == "calibre:rating":
+			if rating, err := strconv.ParseFloat(meta.Content, 64); err == nil {
+				pub.setProperty(name, rating)
+			}
+		case name == "calibre:author_link_map",
+			strings.HasPrefix(name, "calibre:user_metadata"):
+			var value interface{}
+			if err := json.Unmarshal([]byte(meta.Content), &value); err != nil {
+				return nil, fmt.Errorf("bad %s: %v", name, err)
+			}
+			pub.setProperty(name, value)
+		case strings.HasPrefix(name, "calibre:"):
+			pub.setProperty(name, meta.Content)
+		}
+	}
+
+	for _, ref := range opf.Guide {
+		if ref.Type == "cover" {
+			pub.CoverImage = &CoverImage{Path: ref.Href}
+		}
+	}
+
+	return pub, nil
+}
+
+// LoadCalibre return publication metadata from Calibre metadata.opf file.
+func LoadCalibre(filename string) (*Publication, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCalibre(data)
+}
+
+// calibreDate return date from Calibre timestamp. Calibre uses year 101
+// for undefined dates.
+func calibreDate(value string) Date {
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(value))
+	if err != nil {
+		if err := checkDateFormat(value); err != nil {
+			return ""
+		}
+		return Date(value)
+	}
+	if t.Year() < 1000 {
+		return ""
+	}
+	return Date(t.Format("2006-01-02"))
+}
+
+// setProperty set publication extension property value.
+func (p *Publication) setProperty(name string, value interface{}) {
+	if p.Properties == nil {
+		p.Properties = make(map[string]interface{})
+	}
+	p.Properties[name] = value
+}
@@ -0,0 +1,72 @@
+package metadata
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// charsets are the upper halves (0x80-0xFF) of supported single-byte
+// Cyrillic charsets.
+var charsets = map[string][]rune{
+	"windows-1251": []rune(windows1251),
+	"koi8-r":       []rune(koi8r),
+}
+
+const windows1251 = "" +
+	"ЂЃ‚ѓ„…†‡€‰Љ‹ЊЌЋЏ" +
+	"ђ‘’“”•–—\ufffd™љ›њќћџ" +
+	"\u00a0ЎўЈ¤Ґ¦§Ё©Є«¬\u00ad®Ї" +
+	"°±Ііґµ¶·ё№є»јЅѕї" +
+	"АБВГДЕЖЗИЙКЛМНОП" +
+	"РСТУФХЦЧШЩЪЫЬЭЮЯ" +
+	"абвгдежзийклмноп" +
+	"рстуфхцчшщъыьэюя"
+
+const koi8r = "" +
+	"─│┌┐└┘├┤┬┴┼▀▄█▌▐" +
+	"░▒▓⌠■∙√≈≤≥\u00a0⌡°²·÷" +
+	"═║╒ё╓╔╕╖╗╘╙╚╛╜╝╞" +
+	"╟╠╡Ё╢╣╤╥╦╧╨╩╪╫╬©" +
+	"юабцдефгхийклмно" +
+	"пярстужвьызшэщчъ" +
+	"ЮАБЦДЕФГХИЙКЛМНО" +
+	"ПЯРСТУЖВЬЫЗШЭЩЧЪ"
+
+// charsetAliases maps other names of supported charsets.
+var charsetAliases = map[string]string{
+	"cp1251":  "windows-1251",
+	"cp-1251": "windows-1251",
+	"koi8r":   "koi8-r",
+}
+
+// charsetReader return UTF-8 reader for input in UTF-8 or single-byte
+// Cyrillic charset. It is used as xml.Decoder CharsetReader.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	var name = strings.ToLower(charset)
+	if alias, ok := charsetAliases[name]; ok {
+		name = alias
+	}
+	if name == "utf-8" || name == "utf8" {
+		return input, nil
+	}
+	table, ok := charsets[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(data) * 2)
+	for _, c := range data {
+		if c < 0x80 {
+			buf.WriteByte(c)
+		} else {
+			buf.WriteRune(table[c-0x80])
+		}
+	}
+	return &buf, nil
+}
@@ -0,0 +1,358 @@
+package metadata
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ComicInfo is a ComicInfo.xml document used by comic book archives (CBZ).
+//
+// The collection position is used as Number. Fields without publication
+// counterpart (Count, Volume, AgeRating, etc.) are taken from Properties
+// with "comicinfo:" prefix, for example comicinfo:Volume.
+//
+// https://anansi-project.github.io/docs/comicinfo/schemas/v2.1
+type ComicInfo struct {
+	XMLName     xml.Name           `xml:"ComicInfo"`
+	XSI         string             `xml:"xmlns:xsi,attr,omitempty"`
+	XSD         string             `xml:"xmlns:xsd,attr,omitempty"`
+	Title       string             `xml:"Title,omitempty"`
+	Series      string             `xml:"Series,omitempty"`
+	Number      string             `xml:"Number,omitempty"`
+	Count       string             `xml:"Count,omitempty"`
+	Volume      string             `xml:"Volume,omitempty"`
+	Summary     string             `xml:"Summary,omitempty"`
+	Year        string             `xml:"Year,omitempty"`
+	Month       string             `xml:"Month,omitempty"`
+	Day         string             `xml:"Day,omitempty"`
+	Writer      string             `xml:"Writer,omitempty"`
+	Penciller   string             `xml:"Penciller,omitempty"`
+	Inker       string             `xml:"Inker,omitempty"`
+	Colorist    string             `xml:"Colorist,omitempty"`
+	Letterer    string             `xml:"Letterer,omitempty"`
+	CoverArtist string             `xml:"CoverArtist,omitempty"`
+	Editor      string             `xml:"Editor,omitempty"`
+	Translator  string             `xml:"Translator,omitempty"`
+	Publisher   string             `xml:"Publisher,omitempty"`
+	Genre       string             `xml:"Genre,omitempty"`
+	Tags        string             `xml:"Tags,omitempty"`
+	Web         string             `xml:"Web,omitempty"`
+	LanguageISO string             `xml:"LanguageISO,omitempty"`
+	Manga       string             `xml:"Manga,omitempty"` // Unknown, No, Yes or YesAndRightToLeft
+	GTIN        string             `xml:"GTIN,omitempty"`
+	Extra       []ComicInfoElement `xml:",any"`
+}
+
+// ComicInfoElement is an additional ComicInfo element.
+type ComicInfoElement struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+// ComicInfoRoles is a ComicInfo creators fields with the corresponding
+// author roles: MARC relator codes or, when there is no such code, the
+// field name with "comicinfo:" prefix.
+var ComicInfoRoles = map[string][]string{
+	"Writer":      {"aut"},
+	"Penciller":   {"art", "ill", "comicinfo:Penciller"},
+	"Inker":       {"comicinfo:Inker"},
+	"Colorist":    {"clr"},
+	"Letterer":    {"comicinfo:Letterer"},
+	"CoverArtist": {"cov"},
+	"Editor":      {"edt"},
+	"Translator":  {"trl"},
+}
+
+// comicInfoLabel return true if role is a ComicInfo role without MARC
+// relator code, like "comicinfo:Inker".
+func comicInfoLabel(role string) bool {
+	switch role {
+	case "comicinfo:Penciller", "comicinfo:Inker", "comicinfo:Letterer":
+		return true
+	}
+	return false
+}
+
+// comicInfoRoles return ComicInfo fields names for author.
+func (p Publication) comicInfoRoles(author Author, creator bool) []string {
+	var codes = author.Relators(p.Language)
+	for _, role := range author.Role {
+		if comicInfoLabel(role) {
+			codes = append(codes, role)
+		}
+	}
+	if len(codes) == 0 && creator {
+		codes = []string{"aut"}
+	}
+	var fields []string
+	for field, roles := range ComicInfoRoles {
+	roles:
+		for _, role := range roles {
+			for _, code := range codes {
+				if role == code {
+					fields = append(fields, field)
+					break roles
+				}
+			}
+		}
+	}
+	return fields
+}
+
+// ComicInfo return publication metadata as ComicInfo.xml document.
+func (p Publication) ComicInfo() *ComicInfo {
+	var ci = &ComicInfo{
+		XSI:         "http://www.w3.org/2001/XMLSchema-instance",
+		XSD:         "http://www.w3.org/2001/XMLSchema",
+		Title:       p.Title.Main(),
+		Series:      p.BelongsToCollection,
+		Number:      p.GroupPosition,
+		Summary:     p.DescriptionText(),
+		Publisher:   p.Publisher,
+		Genre:       strings.Join(p.Subject, ", "),
+		LanguageISO: p.Language,
+	}
+
+	// date parts
+	if parts := strings.SplitN(string(p.Date), "-", 3); p.Date != "" {
+		ci.Year = parts[0]
+		if len(parts) > 1 {
+			ci.Month = strings.TrimLeft(parts[1], "0")
+		}
+		if len(parts) > 2 && len(parts[2]) >= 2 {
+			ci.Day = strings.TrimLeft(parts[2][:2], "0") // skip time
+		}
+	}
+
+	// creators
+	var people = make(map[string][]string)
+	add := func(field, name string) {
+		for _, item := range people[field] {
+			if item == name {
+				return
+			}
+		}
+		people[field] = append(people[field], name)
+	}
+	for _, author := range p.Creator.Sorted() {
+		for _, field := range p.comicInfoRoles(author, true) {
+			add(field, author.Text)
+		}
+	}
+	for _, author := range p.Contributor.Sorted() {
+		for _, field := range p.comicInfoRoles(author, false) {
+			add(field, author.Text)
+		}
+	}
+	for field, target := range map[string]*string{
+		"Writer":      &ci.Writer,
+		"Penciller":   &ci.Penciller,
+		"Inker":       &ci.Inker,
+		"Colorist":    &ci.Colorist,
+		"Letterer":    &ci.Letterer,
+		"CoverArtist": &ci.CoverArtist,
+		"Editor":      &ci.Editor,
+		"Translator":  &ci.Translator,
+	} {
+		*target = strings.Join(people[field], ", ")
+	}
+
+	// identifiers
+	for _, id := range p.Identifier {
+		var scheme = strings.ToUpper(id.Scheme)
+		switch {
+		case ci.GTIN == "" && (scheme == "ISBN-13" || strings.HasPrefix(scheme, "GTIN") ||
+			scheme == "UPC"):
+			ci.GTIN = strings.ReplaceAll(id.Text, "-", "")
+		case ci.Web == "" && (scheme == "URL" || strings.HasPrefix(id.Text, "http://") ||
+			strings.HasPrefix(id.Text, "https://")):
+			ci.Web = id.Text
+		}
+	}
+
+	if p.PageDirection == "rtl" {
+		ci.Manga = "YesAndRightToLeft"
+	}
+
+	// comicinfo properties
+	var names = make([]string, 0, len(p.Properties))
+	for name := range p.Properties {
+		if strings.HasPrefix(name, "comicinfo:") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var value = fmt.Sprint(p.Properties[name])
+		switch name = strings.TrimPrefix(name, "comicinfo:"); name {
+		case "Count":
+			ci.Count = value
+		case "Volume":
+			ci.Volume = value
+		case "Tags":
+			ci.Tags = value
+		case "Manga":
+			if ci.Manga == "" {
+				ci.Manga = value
+			}
+		default:
+			ci.Extra = append(ci.Extra, ComicInfoElement{
+				XMLName: xml.Name{Local: name}, Value: value})
+		}
+	}
+
+	return ci
+}
+
+// ParseComicInfo return publication metadata from ComicInfo.xml data.
+func ParseComicInfo(data []byte) (*Publication, error) {
+	var ci ComicInfo
+	if err := xml.Unmarshal(data, &ci); err != nil {
+		return nil, err
+	}
+	return ci.Publication(), nil
+}
+
+// LoadComicInfo return publication metadata from ComicInfo.xml file or
+// from comic book archive (CBZ) containing it.
+func LoadComicInfo(filename string) (*Publication, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return ParseComicInfo(data)
+	}
+
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range z.File {
+		if !strings.EqualFold(file.Name, "ComicInfo.xml") {
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+		return ParseComicInfo(data)
+	}
+	return nil, fmt.Errorf("%s: ComicInfo.xml not found", filename)
+}
+
+// Publication return publication metadata from ComicInfo.
+func (ci ComicInfo) Publication() *Publication {
+	var pub = &Publication{
+		BelongsToCollection: strings.TrimSpace(ci.Series),
+		GroupPosition:       strings.TrimSpace(ci.Number),
+		Description:         strings.TrimSpace(ci.Summary),
+		Publisher:           strings.TrimSpace(ci.Publisher),
+		Language:            strings.TrimSpace(ci.LanguageISO),
+	}
+
+	if title := strings.TrimSpace(ci.Title); title != "" {
+		pub.Title = Titles{{Type: "main", Text: title}}
+	}
+
+	// date
+	if year, err := strconv.Atoi(strings.TrimSpace(ci.Year)); err == nil && year > 0 {
+		var date = fmt.Sprintf("%04d", year)
+		if month, err := strconv.Atoi(strings.TrimSpace(ci.Month)); err == nil && month > 0 {
+			date += fmt.Sprintf("-%02d", month)
+			if day, err := strconv.Atoi(strings.TrimSpace(ci.Day)); err == nil && day > 0 {
+				date += fmt.Sprintf("-%02d", day)
+			}
+		}
+		if checkDateFormat(date) == nil {
+			pub.Date = Date(date)
+		}
+	}
+
+	// creators: writers and pencillers are creators, others are contributors
+	for _, field := range []struct {
+		value   string
+		role    string
+		creator bool
+	}{
+		{ci.Writer, "aut", true},
+		{ci.Penciller, "art", true},
+		{ci.Inker, "comicinfo:Inker", false},
+		{ci.Colorist, "clr", false},
+		{ci.Letterer, "comicinfo:Letterer", false},
+		{ci.CoverArtist, "cov", false},
+		{ci.Editor, "edt", false},
+		{ci.Translator, "trl", false},
+	} {
+		for _, name := range strings.Split(field.value, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			var author = Author{Text: name, Role: Strings{field.role}}
+			if field.creator || pub.Creator.has(name) {
+				pub.Creator = pub.Creator.merge(author)
+			} else {
+				pub.Contributor = pub.Contributor.merge(author)
+			}
+		}
+	}
+
+	for _, genre := range strings.Split(ci.Genre, ",") {
+		if genre = strings.TrimSpace(genre); genre != "" {
+			pub.Subject = append(pub.Subject, genre)
+		}
+	}
+
+	if gtin := strings.TrimSpace(ci.GTIN); gtin != "" {
+		var id = Identifier{Scheme: "GTIN-13", Text: gtin}
+		switch {
+		case len(gtin) == 13 && (strings.HasPrefix(gtin, "978") || strings.HasPrefix(gtin, "979")):
+			id.Scheme = "ISBN-13"
+		case len(gtin) == 12:
+			id.Scheme = "UPC"
+		case len(gtin) == 14:
+			id.Scheme = "GTIN-14"
+		}
+		pub.Identifier = append(pub.Identifier, id)
+	}
+	if web := strings.TrimSpace(ci.Web); web != "" {
+		pub.Identifier = append(pub.Identifier, Identifier{Scheme: "URL", Text: web})
+	}
+
+	switch manga := strings.TrimSpace(ci.Manga); manga {
+	case "YesAndRightToLeft":
+		pub.PageDirection = "rtl"
+	case "", "Unknown":
+	default:
+		pub.setProperty("comicinfo:Manga", manga)
+	}
+
+	for name, value := range map[string]string{
+		"Count":  ci.Count,
+		"Volume": ci.Volume,
+		"Tags":   ci.Tags,
+	} {
+		if value = strings.TrimSpace(value); value != "" {
+			pub.setProperty("comicinfo:"+name, value)
+		}
+	}
+	for _, el := range ci.Extra {
+		if value := strings.TrimSpace(el.Value); value != "" {
+			pub.setProperty("comicinfo:"+el.XMLName.Local, value)
+		}
+	}
+
+	return pub
+}
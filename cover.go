@@ -0,0 +1,279 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"image"
+	_ "image/jpeg" // image config decoders
+	_ "image/png"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	epub "github.com/mdigger/epub3"
+	"gopkg.in/yaml.v3"
+)
+
+// CoverImage is a publication cover image. In YAML it may be defined by
+// path only.
+type CoverImage struct {
+	Path       string       `yaml:"path"`
+	MediaType  string       `yaml:"media-type,omitempty"`
+	Width      int          `yaml:"width,omitempty"`
+	Height     int          `yaml:"height,omitempty"`
+	Alt        string       `yaml:"alt,omitempty"` // alternative text for accessibility
+	Thumbnails []CoverImage `yaml:"thumbnails,omitempty"`
+}
+
+type coverType CoverImage // alias
+
+// MarshalYAML implement yaml.Marshaler interface.
+func (c CoverImage) MarshalYAML() (interface{}, error) {
+	if c.MediaType == "" && c.Width == 0 && c.Height == 0 && c.Alt == "" &&
+		len(c.Thumbnails) == 0 {
+		return c.Path, nil
+	}
+	return coverType(c), nil
+}
+
+// UnmarshalYAML implement yaml.Unmarshaler interface.
+func (c *CoverImage) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		*c = CoverImage{Path: value.Value}
+	case yaml.MappingNode:
+		if err := value.Decode((*coverType)(c)); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("line %d: unsupported cover image type: %v", value.Line, value.Kind)
+	}
+	if c.Path == "" {
+		return fmt.Errorf("line %d: empty cover image path", value.Line)
+	}
+	return nil
+}
+
+// coverMediaTypes are the supported cover image media types by file
+// extension.
+var coverMediaTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".webp": "image/webp",
+	".svg":  "image/svg+xml",
+}
+
+// Type return the cover image media type: defined one or by file extension.
+func (c CoverImage) Type() string {
+	if c.MediaType != "" {
+		return c.MediaType
+	}
+	return coverMediaTypes[strings.ToLower(path.Ext(c.Path))]
+}
+
+// Item return EPUB manifest item for the cover image with cover-image
+// property.
+func (c CoverImage) Item() epub.Item {
+	return epub.Item{
+		ID:         CoverImageID,
+		Href:       c.Path,
+		MediaType:  c.Type(),
+		Properties: "cover-image",
+	}
+}
+
+// Items return EPUB manifest items for the cover image and thumbnails.
+func (c CoverImage) Items() []epub.Item {
+	var items = []epub.Item{c.Item()}
+	for i, thumb := range c.Thumbnails {
+		items = append(items, epub.Item{
+			ID:        fmt.Sprintf("%s-thumb-%02d", CoverImageID, i+1),
+			Href:      thumb.Path,
+			MediaType: thumb.Type(),
+		})
+	}
+	return items
+}
+
+// Sniff read the cover image and thumbnails files headers and set the
+// media type and dimensions if they are not defined. The relative path is
+// resolved from dir. All images are sniffed, the first error is returned.
+func (c *CoverImage) Sniff(dir string) (err error) {
+	mediaType, width, height, err := sniffImage(coverFile(dir, c.Path))
+	if err == nil {
+		if c.MediaType == "" {
+			c.MediaType = mediaType
+		}
+		if c.Width == 0 && c.Height == 0 {
+			c.Width, c.Height = width, height
+		}
+	}
+	for i := range c.Thumbnails {
+		if thumbErr := c.Thumbnails[i].Sniff(dir); err == nil {
+			err = thumbErr
+		}
+	}
+	return err
+}
+
+// coverFile return the cover image file name relative to dir.
+func coverFile(dir, name string) string {
+	name = filepath.FromSlash(name)
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(dir, name)
+}
+
+// sniffImage return the image media type and dimensions by file header.
+// Only JPEG, PNG, WebP and SVG images are supported.
+func sniffImage(filename string) (mediaType string, width, height int, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	defer file.Close()
+	header := make([]byte, 512)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", 0, 0, err
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte("\xff\xd8\xff")),
+		bytes.HasPrefix(header, []byte("\x89PNG\r\n\x1a\n")):
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return "", 0, 0, err
+		}
+		config, format, err := image.DecodeConfig(file)
+		if err != nil {
+			return "", 0, 0, fmt.Errorf("%s: %w", filename, err)
+		}
+		return "image/" + format, config.Width, config.Height, nil
+
+	case len(header) >= 30 && string(header[:4]) == "RIFF" && string(header[8:12]) == "WEBP":
+		width, height = webpSize(header)
+		return "image/webp", width, height, nil
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", 0, 0, err
+	}
+	if width, height, ok := svgSize(file); ok {
+		return "image/svg+xml", width, height, nil
+	}
+	return "", 0, 0, fmt.Errorf("%s: unsupported cover image format", filename)
+}
+
+// webpSize return WebP image dimensions from the file header.
+func webpSize(header []byte) (width, height int) {
+	var chunk = header[12:]
+	switch string(chunk[:4]) {
+	case "VP8 ": // lossy
+		if len(chunk) >= 18 {
+			width = int(binary.LittleEndian.Uint16(chunk[14:]) & 0x3fff)
+			height = int(binary.LittleEndian.Uint16(chunk[16:]) & 0x3fff)
+		}
+	case "VP8L": // lossless
+		if len(chunk) >= 13 {
+			bits := binary.LittleEndian.Uint32(chunk[9:])
+			width, height = int(bits&0x3fff)+1, int(bits>>14&0x3fff)+1
+		}
+	case "VP8X": // extended
+		if len(chunk) >= 18 {
+			width = int(uint32(chunk[12])|uint32(chunk[13])<<8|uint32(chunk[14])<<16) + 1
+			height = int(uint32(chunk[15])|uint32(chunk[16])<<8|uint32(chunk[17])<<16) + 1
+		}
+	}
+	return width, height
+}
+
+var reSVGLength = regexp.MustCompile(`^\s*(\d+(?:\.\d+)?)\s*(?:px)?\s*$`)
+
+// svgSize return SVG image dimensions from the root element width and
+// height or viewBox attributes. Ok is false if the root element is not svg.
+func svgSize(r io.Reader) (width, height int, ok bool) {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return 0, 0, false
+		}
+		start, isStart := token.(xml.StartElement)
+		if !isStart {
+			continue
+		}
+		if start.Name.Local != "svg" {
+			return 0, 0, false
+		}
+		length := func(s string) int {
+			if match := reSVGLength.FindStringSubmatch(s); match != nil {
+				f, _ := strconv.ParseFloat(match[1], 64)
+				return int(f + 0.5)
+			}
+			return 0
+		}
+		var viewBox []string
+		for _, attr := range start.Attr {
+			switch attr.Name.Local {
+			case "width":
+				width = length(attr.Value)
+			case "height":
+				height = length(attr.Value)
+			case "viewBox":
+				viewBox = strings.Fields(strings.ReplaceAll(attr.Value, ",", " "))
+			}
+		}
+		if (width == 0 || height == 0) && len(viewBox) == 4 {
+			width, height = length(viewBox[2]), length(viewBox[3])
+		}
+		return width, height, true
+	}
+}
+
+// validateCover check the cover image alternative text.
+func validateCover(cover *CoverImage) (warnings []Warning) {
+	if cover != nil && cover.Alt == "" {
+		warnings = append(warnings, Warning{
+			Field:   "cover-image",
+			Message: "no alternative text for the cover image",
+		})
+	}
+	return warnings
+}
+
+// validateCoverFiles check the cover image files exist relative to dir and
+// are JPEG, PNG, WebP or SVG images.
+func validateCoverFiles(cover *CoverImage, dir string) (warnings []Warning) {
+	if cover == nil {
+		return nil
+	}
+	var images = append([]CoverImage{*cover}, cover.Thumbnails...)
+	for i, img := range images {
+		var field = "cover-image"
+		if i > 0 {
+			field = fmt.Sprintf("cover-image.thumbnails[%d]", i-1)
+		}
+		mediaType, _, _, err := sniffImage(coverFile(dir, img.Path))
+		if err != nil {
+			warnings = append(warnings, Warning{Field: field, Message: err.Error()})
+			continue
+		}
+		if img.MediaType != "" && img.MediaType != mediaType {
+			warnings = append(warnings, Warning{
+				Field:   field,
+				Message: fmt.Sprintf("media type %q differ from file type %q", img.MediaType, mediaType),
+			})
+		}
+	}
+	return warnings
+}
@@ -51,3 +51,25 @@ func checkDateFormat(d string) (err error) {
 
 	return nil
 }
+
+// EPUBModified return the date in CCYY-MM-DDThh:mm:ssZ form required for the
+// EPUB dcterms:modified property. The time of a date without time is
+// midnight. Year or month only dates can't be converted.
+func (date Date) EPUBModified() (string, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, string(date)); err == nil {
+			return t.UTC().Format("2006-01-02T15:04:05Z"), true
+		}
+	}
+	return "", false
+}
+
+// Time return the parsed date. Missing month and day are the first ones.
+func (date Date) Time() (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02", "2006-01", "2006"} {
+		if t, err := time.Parse(layout, string(date)); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
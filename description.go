@@ -0,0 +1,462 @@
+package metadata
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Publication description may be written as Markdown or as HTML. The
+// description is rendered to sanitized XHTML with a limited set of elements
+// or to plain text with paragraphs separated by empty line.
+
+// descriptionTags is a whitelist of XHTML description elements.
+var descriptionTags = map[string]bool{
+	"p": true, "br": true, "em": true, "strong": true, "b": true, "i": true,
+	"u": true, "sub": true, "sup": true, "code": true, "ul": true, "ol": true,
+	"li": true, "blockquote": true, "a": true,
+}
+
+// reHTML detect description written as HTML.
+var reHTML = regexp.MustCompile(`(?i)<(p|div|br|ul|ol|li|em|strong|b|i|a|blockquote|h[1-6]|span)[\s/>]`)
+
+// DescriptionHTML return the description as sanitized XHTML fragment.
+func (p Publication) DescriptionHTML() string {
+	return descriptionHTML(p.Description)
+}
+
+// DescriptionText return the description as plain text with paragraphs
+// separated by empty line.
+func (p Publication) DescriptionText() string {
+	return descriptionText(p.Description)
+}
+
+// Teaser return the short description or the description as single line
+// plain text limited to max characters. The text is cut on the word
+// boundary and ended with ellipsis. Zero max means no limit.
+func (p Publication) Teaser(max int) string {
+	var text = descriptionText(p.ShortDescription)
+	if text == "" {
+		text = descriptionText(p.Description)
+	}
+	return teaser(text, max)
+}
+
+// descriptionHTML return Markdown or HTML text as sanitized XHTML.
+func descriptionHTML(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ""
+	}
+	if reHTML.MatchString(text) {
+		return sanitizeHTML(text)
+	}
+	return markdownHTML(text)
+}
+
+// descriptionText return Markdown or HTML text as plain text.
+func descriptionText(text string) string {
+	return htmlText(descriptionHTML(text))
+}
+
+// teaser return text in a single line limited to max characters.
+func teaser(text string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if max <= 0 || utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	var runes = []rune(text)[:max]
+	var cut = string(runes[:len(runes)-1])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,;:.-") + "…"
+}
+
+// safeURL return true for http, https and mailto links.
+func safeURL(link string) bool {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// htmlEscaper escape XML special characters.
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// escapeHTML return text with XML special characters escaped.
+func escapeHTML(text string) string {
+	return htmlEscaper.Replace(text)
+}
+
+// newHTMLDecoder return not strict XML decoder for HTML fragment.
+func newHTMLDecoder(fragment string) *xml.Decoder {
+	dec := xml.NewDecoder(strings.NewReader("<div>" + fragment + "</div>"))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+	return dec
+}
+
+// sanitizeHTML return HTML fragment with whitelisted elements only. Other
+// elements are removed with their attributes, but the content is kept,
+// except for scripts and styles. Headings are converted to paragraphs.
+func sanitizeHTML(fragment string) string {
+	var (
+		b     strings.Builder
+		open  []string
+		skip  int
+		dec   = newHTMLDecoder(fragment)
+		depth int
+	)
+	closeTo := func(name string) {
+		for i := len(open) - 1; i >= 0; i-- {
+			if open[i] != name {
+				continue
+			}
+			for j := len(open) - 1; j >= i; j-- {
+				fmt.Fprintf(&b, "</%s>", open[j])
+			}
+			open = open[:i]
+			return
+		}
+	}
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			break // io.EOF or broken markup: return what was read
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			var name = strings.ToLower(t.Name.Local)
+			if skip > 0 || name == "script" || name == "style" {
+				skip++
+				continue
+			}
+			if len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6' {
+				name = "p"
+			}
+			if !descriptionTags[name] {
+				continue
+			}
+			if name == "br" {
+				b.WriteString("<br/>")
+				continue
+			}
+			if name == "p" {
+				closeTo("p") // paragraphs can't be nested
+			}
+			b.WriteString("<" + name)
+			if name == "a" {
+				for _, attr := range t.Attr {
+					if strings.EqualFold(attr.Name.Local, "href") && safeURL(attr.Value) {
+						fmt.Fprintf(&b, ` href="%s"`, escapeHTML(strings.TrimSpace(attr.Value)))
+					}
+				}
+			}
+			b.WriteString(">")
+			open = append(open, name)
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				continue // fragment wrapper
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			var name = strings.ToLower(t.Name.Local)
+			if len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6' {
+				name = "p"
+			}
+			if descriptionTags[name] && name != "br" {
+				closeTo(name)
+			}
+		case xml.CharData:
+			if skip == 0 {
+				b.WriteString(escapeHTML(string(t)))
+			}
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		fmt.Fprintf(&b, "</%s>", open[i])
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// htmlText return plain text of sanitized HTML fragment. Block elements are
+// separated by empty line, list items start on new line.
+func htmlText(fragment string) string {
+	if fragment == "" {
+		return ""
+	}
+	type list struct {
+		ordered bool
+		n       int
+	}
+	var (
+		b     strings.Builder
+		lists []*list
+		dec   = newHTMLDecoder(fragment)
+	)
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p", "blockquote":
+				b.WriteString("\n\n")
+			case "ul", "ol":
+				b.WriteString("\n\n")
+				lists = append(lists, &list{ordered: t.Name.Local == "ol"})
+			case "li":
+				b.WriteString("\n")
+				if len(lists) == 0 {
+					b.WriteString("- ")
+					break
+				}
+				var l = lists[len(lists)-1]
+				l.n++
+				if l.ordered {
+					fmt.Fprintf(&b, "%d. ", l.n)
+				} else {
+					b.WriteString("- ")
+				}
+			case "br":
+				b.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p", "blockquote":
+				b.WriteString("\n\n")
+			case "ul", "ol":
+				b.WriteString("\n\n")
+				if len(lists) > 0 {
+					lists = lists[:len(lists)-1]
+				}
+			}
+		case xml.CharData:
+			var text = strings.Join(strings.Fields(string(t)), " ")
+			if text == "" {
+				if len(t) > 0 {
+					b.WriteString(" ")
+				}
+				continue
+			}
+			if first, _ := utf8.DecodeRune(t); first == ' ' || first == '\t' || first == '\n' || first == '\r' {
+				text = " " + text
+			}
+			if last, _ := utf8.DecodeLastRune(t); last == ' ' || last == '\t' || last == '\n' || last == '\r' {
+				text += " "
+			}
+			b.WriteString(text)
+		}
+	}
+
+	// trim lines and remove repeated empty lines
+	var lines []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" && (len(lines) == 0 || lines[len(lines)-1] == "") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+var (
+	reMarkdownHeading = regexp.MustCompile(`^ {0,3}#{1,6}(?:\s+(.*?))?(?:\s+#+)?\s*$`)
+	reMarkdownList    = regexp.MustCompile(`^ {0,3}([-*+]|\d{1,9}[.)])\s+(.*)$`)
+	reMarkdownQuote   = regexp.MustCompile(`^ {0,3}> ?`)
+	reMarkdownRule    = regexp.MustCompile(`^ {0,3}([-*_])(\s*([-*_])){2,}\s*$`)
+	reMarkdownLink    = regexp.MustCompile(`^\[([^\]]+)\]\(([^)\s]+)\)`)
+)
+
+// markdownHTML return Markdown text as XHTML. Only the subset of Markdown
+// is supported: paragraphs with hard line breaks, headings, block quotes,
+// lists, emphasis, code spans and links.
+func markdownHTML(text string) string {
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\t", "    ")
+	return strings.Join(markdownBlocks(strings.Split(text, "\n")), "\n")
+}
+
+// markdownBlocks return the list of XHTML blocks for Markdown lines.
+func markdownBlocks(lines []string) (blocks []string) {
+	isBlockStart := func(line string) bool {
+		return reMarkdownHeading.MatchString(line) || reMarkdownQuote.MatchString(line) ||
+			reMarkdownList.MatchString(line) || reMarkdownRule.MatchString(line)
+	}
+	for i := 0; i < len(lines); {
+		var line = lines[i]
+		switch {
+		case strings.TrimSpace(line) == "", reMarkdownRule.MatchString(line):
+			i++
+
+		case reMarkdownHeading.MatchString(line):
+			var title = reMarkdownHeading.FindStringSubmatch(line)[1]
+			if title != "" {
+				blocks = append(blocks, "<p><strong>"+markdownInline(title)+"</strong></p>")
+			}
+			i++
+
+		case reMarkdownQuote.MatchString(line):
+			var quote []string
+			for ; i < len(lines) && reMarkdownQuote.MatchString(lines[i]); i++ {
+				quote = append(quote, reMarkdownQuote.ReplaceAllString(lines[i], ""))
+			}
+			blocks = append(blocks, "<blockquote>"+
+				strings.Join(markdownBlocks(quote), "\n")+"</blockquote>")
+
+		case reMarkdownList.MatchString(line):
+			var (
+				marker  = reMarkdownList.FindStringSubmatch(line)[1]
+				ordered = marker[0] >= '0' && marker[0] <= '9'
+				items   []string
+			)
+			for i < len(lines) {
+				var line = lines[i]
+				if match := reMarkdownList.FindStringSubmatch(line); match != nil {
+					if (match[1][0] >= '0' && match[1][0] <= '9') != ordered {
+						break // other list type
+					}
+					items = append(items, match[2])
+					i++
+					continue
+				}
+				if strings.TrimSpace(line) == "" {
+					// the list continues after empty line with the next item
+					// or with indented text
+					var j = i + 1
+					for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+						j++
+					}
+					if j < len(lines) && (reMarkdownList.MatchString(lines[j]) ||
+						strings.HasPrefix(lines[j], "  ")) {
+						i = j
+						continue
+					}
+					break
+				}
+				if isBlockStart(line) && !strings.HasPrefix(line, "    ") {
+					break
+				}
+				items[len(items)-1] += "\n" + strings.TrimSpace(line)
+				i++
+			}
+			var tag = "ul"
+			if ordered {
+				tag = "ol"
+			}
+			var b strings.Builder
+			b.WriteString("<" + tag + ">")
+			for _, item := range items {
+				b.WriteString("<li>" + markdownParagraph(item) + "</li>")
+			}
+			b.WriteString("</" + tag + ">")
+			blocks = append(blocks, b.String())
+
+		default:
+			var par []string
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != "" &&
+				(len(par) == 0 || !isBlockStart(lines[i])); i++ {
+				par = append(par, lines[i])
+			}
+			blocks = append(blocks, "<p>"+markdownParagraph(strings.Join(par, "\n"))+"</p>")
+		}
+	}
+	return blocks
+}
+
+// markdownParagraph return paragraph lines as XHTML with hard line breaks
+// for lines ended with two spaces or backslash.
+func markdownParagraph(text string) string {
+	var lines = strings.Split(text, "\n")
+	for i, line := range lines {
+		var last = i == len(lines)-1
+		switch {
+		case !last && strings.HasSuffix(line, "  "):
+			lines[i] = markdownInline(strings.TrimSpace(line)) + "<br/>"
+		case !last && strings.HasSuffix(line, "\\"):
+			lines[i] = markdownInline(strings.TrimSpace(strings.TrimSuffix(line, "\\"))) + "<br/>"
+		default:
+			lines[i] = markdownInline(strings.TrimSpace(line))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// markdownPunct is a list of Markdown characters which can be escaped with
+// backslash.
+const markdownPunct = "\\`*_{}[]()#+-.!<>|~"
+
+// markdownInline return Markdown inline text as XHTML.
+func markdownInline(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		var c = text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && strings.IndexByte(markdownPunct, text[i+1]) >= 0:
+			b.WriteString(escapeHTML(text[i+1 : i+2]))
+			i += 2
+			continue
+
+		case c == '`':
+			if j := strings.IndexByte(text[i+1:], '`'); j > 0 {
+				b.WriteString("<code>" + escapeHTML(text[i+1:i+1+j]) + "</code>")
+				i += j + 2
+				continue
+			}
+
+		case c == '*' || c == '_':
+			if c == '_' && i > 0 && isWordByte(text[i-1]) {
+				break // intraword underscore
+			}
+			var delim, tag = text[i : i+1], "em"
+			if strings.HasPrefix(text[i+1:], delim) {
+				delim, tag = delim+delim, "strong"
+			}
+			var start = i + len(delim)
+			if start >= len(text) || text[start] == ' ' {
+				break
+			}
+			if j := strings.Index(text[start:], delim); j > 0 && text[start+j-1] != ' ' {
+				b.WriteString("<" + tag + ">" + markdownInline(text[start:start+j]) + "</" + tag + ">")
+				i = start + j + len(delim)
+				continue
+			}
+
+		case c == '[':
+			if match := reMarkdownLink.FindStringSubmatch(text[i:]); match != nil {
+				if safeURL(match[2]) {
+					fmt.Fprintf(&b, `<a href="%s">%s</a>`, escapeHTML(match[2]), markdownInline(match[1]))
+				} else {
+					b.WriteString(markdownInline(match[1]))
+				}
+				i += len(match[0])
+				continue
+			}
+		}
+		b.WriteString(escapeHTML(text[i : i+1])) // UTF-8 bytes are kept as is
+		i++
+	}
+	return b.String()
+}
+
+// isWordByte return true for ASCII letters and digits and for UTF-8
+// multibyte characters.
+func isWordByte(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
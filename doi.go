@@ -0,0 +1,470 @@
+package metadata
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// reDOI is a DOI syntax: 10.<registrant>/<suffix>.
+var reDOI = regexp.MustCompile(`^10\.\d{4,9}(\.\d+)*/\S+$`)
+
+// normalizeDOI return DOI without doi: or resolver URL prefix.
+func normalizeDOI(text string) string {
+	text = strings.TrimSpace(text)
+	for _, prefix := range []string{
+		"doi:", "https://doi.org/", "http://doi.org/",
+		"https://dx.doi.org/", "http://dx.doi.org/", "urn:doi:",
+	} {
+		if len(text) > len(prefix) && strings.EqualFold(text[:len(prefix)], prefix) {
+			return text[len(prefix):]
+		}
+	}
+	return text
+}
+
+// DOI return the publication DOI. Return error if DOI identifier is not
+// defined or is malformed.
+func (p Publication) DOI() (string, error) {
+	for _, id := range p.Identifier {
+		if !strings.EqualFold(id.Scheme, "DOI") {
+			continue
+		}
+		doi := normalizeDOI(id.Text)
+		if !reDOI.MatchString(doi) {
+			return "", fmt.Errorf("bad DOI %q", id.Text)
+		}
+		return doi, nil
+	}
+	return "", fmt.Errorf("DOI identifier not defined")
+}
+
+// validateDOI check the format of DOI identifiers.
+func validateDOI(ids Identifiers) (warnings []Warning) {
+	for i, id := range ids {
+		if strings.EqualFold(id.Scheme, "DOI") && !reDOI.MatchString(normalizeDOI(id.Text)) {
+			warnings = append(warnings, Warning{
+				Field:   fmt.Sprintf("identifier[%d]", i),
+				Message: fmt.Sprintf("bad DOI %q", id.Text),
+			})
+		}
+	}
+	return warnings
+}
+
+// DOIDeposit describe the DOI registration details supplied by caller.
+type DOIDeposit struct {
+	BatchID        string    // Crossref batch id, generated when empty
+	Timestamp      time.Time // deposit time, now when zero
+	DepositorName  string
+	DepositorEmail string
+	Registrant     string
+	URL            string // resource URL the DOI resolves to
+}
+
+// CrossrefBatch is a Crossref deposit document for book registration.
+//
+// https://www.crossref.org/documentation/schema-library/
+type CrossrefBatch struct {
+	XMLName        xml.Name     `xml:"http://www.crossref.org/schema/5.3.1 doi_batch"`
+	XSI            string       `xml:"xmlns:xsi,attr"`
+	SchemaLocation string       `xml:"xsi:schemaLocation,attr"`
+	Version        string       `xml:"version,attr"`
+	BatchID        string       `xml:"head>doi_batch_id"`
+	Timestamp      string       `xml:"head>timestamp"`
+	DepositorName  string       `xml:"head>depositor>depositor_name"`
+	DepositorEmail string       `xml:"head>depositor>email_address"`
+	Registrant     string       `xml:"head>registrant"`
+	Book           CrossrefBook `xml:"body>book"`
+}
+
+// CrossrefBook is a Crossref book.
+type CrossrefBook struct {
+	BookType string               `xml:"book_type,attr"`
+	Metadata CrossrefBookMetadata `xml:"book_metadata"`
+}
+
+// CrossrefBookMetadata is a Crossref book metadata.
+type CrossrefBookMetadata struct {
+	Language     string                `xml:"language,attr,omitempty"`
+	Contributors []CrossrefContributor `xml:"contributors>person_name,omitempty"`
+	Title        string                `xml:"titles>title"`
+	Subtitle     string                `xml:"titles>subtitle,omitempty"`
+	Date         CrossrefDate          `xml:"publication_date"`
+	ISBN         []CrossrefISBN        `xml:"isbn,omitempty"`
+	NoISBN       *CrossrefNoISBN       `xml:"noisbn,omitempty"`
+	Publisher    string                `xml:"publisher>publisher_name"`
+	DOI          string                `xml:"doi_data>doi"`
+	Resource     string                `xml:"doi_data>resource"`
+}
+
+// CrossrefContributor is a Crossref person name.
+type CrossrefContributor struct {
+	Sequence  string `xml:"sequence,attr"` // first or additional
+	Role      string `xml:"contributor_role,attr"`
+	GivenName string `xml:"given_name,omitempty"`
+	Surname   string `xml:"surname"`
+}
+
+// CrossrefDate is a Crossref publication date.
+type CrossrefDate struct {
+	MediaType string `xml:"media_type,attr"`
+	Month     string `xml:"month,omitempty"`
+	Day       string `xml:"day,omitempty"`
+	Year      string `xml:"year"`
+}
+
+// CrossrefISBN is a Crossref ISBN.
+type CrossrefISBN struct {
+	MediaType string `xml:"media_type,attr"`
+	Value     string `xml:",chardata"`
+}
+
+// CrossrefNoISBN is a Crossref reason for missing ISBN.
+type CrossrefNoISBN struct {
+	Reason string `xml:"reason,attr"`
+}
+
+// CrossrefRoles is a MARC relator codes to Crossref contributor roles.
+var CrossrefRoles = map[string]string{
+	"aut": "author",
+	"cre": "author",
+	"edt": "editor",
+	"trl": "translator",
+	"rev": "reviewer",
+}
+
+// Crossref return publication metadata as Crossref book deposit.
+func (p Publication) Crossref(d DOIDeposit) (*CrossrefBatch, error) {
+	doi, err := p.DOI()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case d.DepositorName == "" || d.DepositorEmail == "":
+		return nil, fmt.Errorf("depositor name and email are required")
+	case d.Registrant == "":
+		return nil, fmt.Errorf("registrant is required")
+	case d.URL == "":
+		return nil, fmt.Errorf("resource URL is required")
+	}
+	if len(p.Title) == 0 {
+		return nil, fmt.Errorf("title is required")
+	}
+	var year = reYear.FindString(string(p.Date))
+	if year == "" {
+		return nil, fmt.Errorf("publication date is required")
+	}
+	if p.Publisher == "" {
+		return nil, fmt.Errorf("publisher is required")
+	}
+
+	if d.Timestamp.IsZero() {
+		d.Timestamp = time.Now()
+	}
+	if d.BatchID == "" {
+		d.BatchID = fmt.Sprintf("%s-%d", doi, d.Timestamp.Unix())
+	}
+
+	var batch = &CrossrefBatch{
+		XSI: "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLocation: "http://www.crossref.org/schema/5.3.1 " +
+			"https://www.crossref.org/schemas/crossref5.3.1.xsd",
+		Version:        "5.3.1",
+		BatchID:        d.BatchID,
+		Timestamp:      d.Timestamp.UTC().Format("20060102150405"),
+		DepositorName:  d.DepositorName,
+		DepositorEmail: d.DepositorEmail,
+		Registrant:     d.Registrant,
+	}
+	batch.Book.BookType = "monograph"
+	if len(p.Contributor) > 0 && len(p.Creator) == 0 {
+		batch.Book.BookType = "edited_book"
+	}
+	var book = &batch.Book.Metadata
+	book.Language = p.Language
+	book.Title = p.Title.Main()
+	for _, title := range p.Title {
+		if title.Type == "subtitle" {
+			book.Subtitle = title.Text
+			break
+		}
+	}
+	book.Publisher = p.Publisher
+	book.DOI = doi
+	book.Resource = d.URL
+
+	// contributors
+	addContributor := func(author Author, defaultRole string) {
+		var role = defaultRole
+		for _, code := range author.Relators(p.Language) {
+			if r, ok := CrossrefRoles[code]; ok {
+				role = r
+				break
+			}
+		}
+		if role == "" {
+			return
+		}
+		var name = fb2Author(author)
+		var c = CrossrefContributor{
+			Sequence:  "additional",
+			Role:      role,
+			GivenName: strings.TrimSpace(name.FirstName + " " + name.MiddleName),
+			Surname:   name.LastName,
+		}
+		if c.Surname == "" {
+			c.Surname = author.Text
+		}
+		if len(book.Contributors) == 0 {
+			c.Sequence = "first"
+		}
+		book.Contributors = append(book.Contributors, c)
+	}
+	for _, author := range p.Creator.Sorted() {
+		addContributor(author, "author")
+	}
+	for _, author := range p.Contributor.Sorted() {
+		addContributor(author, "")
+	}
+
+	// date
+	var parts = strings.SplitN(string(p.Date), "-", 3)
+	book.Date = CrossrefDate{MediaType: "online", Year: year}
+	if len(parts) > 1 {
+		book.Date.Month = parts[1]
+	}
+	if len(parts) > 2 && len(parts[2]) >= 2 {
+		book.Date.Day = parts[2][:2]
+	}
+
+	// isbn
+	for _, id := range p.Identifier {
+		if strings.HasPrefix(strings.ToUpper(id.Scheme), "ISBN") {
+			var isbn = strings.TrimPrefix(id.Text, "urn:isbn:")
+			isbn = strings.NewReplacer("-", "", " ", "").Replace(isbn)
+			book.ISBN = append(book.ISBN, CrossrefISBN{MediaType: "electronic", Value: isbn})
+		}
+	}
+	if len(book.ISBN) == 0 {
+		book.NoISBN = &CrossrefNoISBN{Reason: "monograph"}
+	}
+
+	return batch, nil
+}
+
+// DataCiteResource is a DataCite Metadata Schema 4 resource.
+//
+// https://schema.datacite.org/meta/kernel-4/
+type DataCiteResource struct {
+	XMLName              xml.Name                `xml:"http://datacite.org/schema/kernel-4 resource"`
+	XSI                  string                  `xml:"xmlns:xsi,attr"`
+	SchemaLocation       string                  `xml:"xsi:schemaLocation,attr"`
+	Identifier           DataCiteIdentifier      `xml:"identifier"`
+	Creators             []DataCiteCreator       `xml:"creators>creator"`
+	Titles               []DataCiteTitle         `xml:"titles>title"`
+	Publisher            string                  `xml:"publisher"`
+	PublicationYear      string                  `xml:"publicationYear"`
+	ResourceType         DataCiteResourceType    `xml:"resourceType"`
+	Subjects             []string                `xml:"subjects>subject,omitempty"`
+	Contributors         []DataCiteContributor   `xml:"contributors>contributor,omitempty"`
+	Dates                []DataCiteDate          `xml:"dates>date,omitempty"`
+	Language             string                  `xml:"language,omitempty"`
+	AlternateIdentifiers []DataCiteAltIdentifier `xml:"alternateIdentifiers>alternateIdentifier,omitempty"`
+	Version              string                  `xml:"version,omitempty"`
+	Rights               []DataCiteRights        `xml:"rightsList>rights,omitempty"`
+	Descriptions         []DataCiteDescription   `xml:"descriptions>description,omitempty"`
+}
+
+// DataCiteIdentifier is a DataCite identifier.
+type DataCiteIdentifier struct {
+	Type  string `xml:"identifierType,attr"`
+	Value string `xml:",chardata"`
+}
+
+// DataCiteCreator is a DataCite creator.
+type DataCiteCreator struct {
+	Name       DataCiteName `xml:"creatorName"`
+	GivenName  string       `xml:"givenName,omitempty"`
+	FamilyName string       `xml:"familyName,omitempty"`
+}
+
+// DataCiteContributor is a DataCite contributor.
+type DataCiteContributor struct {
+	Type       string       `xml:"contributorType,attr"`
+	Name       DataCiteName `xml:"contributorName"`
+	GivenName  string       `xml:"givenName,omitempty"`
+	FamilyName string       `xml:"familyName,omitempty"`
+}
+
+// DataCiteName is a DataCite creator or contributor name.
+type DataCiteName struct {
+	Type  string `xml:"nameType,attr,omitempty"` // Personal or Organizational
+	Value string `xml:",chardata"`
+}
+
+// DataCiteTitle is a DataCite title.
+type DataCiteTitle struct {
+	Type  string `xml:"titleType,attr,omitempty"`
+	Lang  string `xml:"xml:lang,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+// DataCiteResourceType is a DataCite resource type.
+type DataCiteResourceType struct {
+	General string `xml:"resourceTypeGeneral,attr"`
+	Value   string `xml:",chardata"`
+}
+
+// DataCiteDate is a DataCite date.
+type DataCiteDate struct {
+	Type  string `xml:"dateType,attr"`
+	Value string `xml:",chardata"`
+}
+
+// DataCiteAltIdentifier is a DataCite alternate identifier.
+type DataCiteAltIdentifier struct {
+	Type  string `xml:"alternateIdentifierType,attr"`
+	Value string `xml:",chardata"`
+}
+
+// DataCiteRights is a DataCite rights statement.
+type DataCiteRights struct {
+	URI              string `xml:"rightsURI,attr,omitempty"`
+	Identifier       string `xml:"rightsIdentifier,attr,omitempty"`
+	IdentifierScheme string `xml:"rightsIdentifierScheme,attr,omitempty"`
+	Value            string `xml:",chardata"`
+}
+
+// DataCiteDescription is a DataCite description.
+type DataCiteDescription struct {
+	Type  string `xml:"descriptionType,attr"`
+	Value string `xml:",chardata"`
+}
+
+// DataCiteRoles is a MARC relator codes to DataCite contributor types.
+// Other roles are written as "Other".
+var DataCiteRoles = map[string]string{
+	"edt": "Editor",
+	"pro": "Producer",
+	"dst": "Distributor",
+	"cph": "RightsHolder",
+	"spn": "Sponsor",
+	"ths": "Supervisor",
+	"res": "Researcher",
+}
+
+// DataCite return publication metadata as DataCite resource. The resource
+// URL is supplied to DataCite separately and is not the part of metadata.
+func (p Publication) DataCite() (*DataCiteResource, error) {
+	doi, err := p.DOI()
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Title) == 0 {
+		return nil, fmt.Errorf("title is required")
+	}
+	if len(p.Creator) == 0 {
+		return nil, fmt.Errorf("creator is required")
+	}
+	var year = reYear.FindString(string(p.Date))
+	if year == "" {
+		return nil, fmt.Errorf("publication date is required")
+	}
+	if p.Publisher == "" {
+		return nil, fmt.Errorf("publisher is required")
+	}
+
+	var res = &DataCiteResource{
+		XSI: "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLocation: "http://datacite.org/schema/kernel-4 " +
+			"http://schema.datacite.org/meta/kernel-4/metadata.xsd",
+		Identifier:      DataCiteIdentifier{Type: "DOI", Value: doi},
+		Publisher:       p.Publisher,
+		PublicationYear: year,
+		ResourceType:    DataCiteResourceType{General: "Book", Value: p.Type},
+		Subjects:        p.Subject,
+		Language:        p.Language,
+		Version:         string(p.Version),
+	}
+
+	person := func(author Author) DataCiteCreator {
+		var name = fb2Author(author)
+		var result = DataCiteCreator{
+			Name:       DataCiteName{Type: "Personal", Value: author.Text},
+			GivenName:  strings.TrimSpace(name.FirstName + " " + name.MiddleName),
+			FamilyName: name.LastName,
+		}
+		if author.FileAs != "" {
+			result.Name.Value = author.FileAs
+		}
+		if result.FamilyName == "" {
+			result.Name.Type = "" // unknown name type
+		}
+		return result
+	}
+	for _, author := range p.Creator.Sorted() {
+		res.Creators = append(res.Creators, person(author))
+	}
+	for _, author := range p.Contributor.Sorted() {
+		var creator = person(author)
+		var c = DataCiteContributor{
+			Type:       "Other",
+			Name:       creator.Name,
+			GivenName:  creator.GivenName,
+			FamilyName: creator.FamilyName,
+		}
+		for _, code := range author.Relators(p.Language) {
+			if t, ok := DataCiteRoles[code]; ok {
+				c.Type = t
+				break
+			}
+		}
+		res.Contributors = append(res.Contributors, c)
+	}
+
+	for _, title := range p.Title {
+		var t = DataCiteTitle{Lang: title.Lang, Value: title.Text}
+		switch title.Type {
+		case "", "main":
+		case "subtitle":
+			t.Type = "Subtitle"
+		default:
+			t.Type = "AlternativeTitle"
+		}
+		res.Titles = append(res.Titles, t)
+	}
+
+	res.Dates = append(res.Dates, DataCiteDate{Type: "Issued", Value: string(p.Date)})
+	if p.Modified != "" {
+		res.Dates = append(res.Dates, DataCiteDate{Type: "Updated", Value: string(p.Modified)})
+	}
+
+	for _, id := range p.Identifier {
+		if strings.EqualFold(id.Scheme, "DOI") || id.Scheme == "" {
+			continue
+		}
+		var scheme = id.Scheme
+		if strings.HasPrefix(strings.ToUpper(scheme), "ISBN") {
+			scheme = "ISBN"
+		}
+		res.AlternateIdentifiers = append(res.AlternateIdentifiers,
+			DataCiteAltIdentifier{Type: scheme, Value: id.Text})
+	}
+
+	if license := p.license(); p.RightsText() != "" || license.LicenseURL() != "" {
+		var dr = DataCiteRights{URI: license.LicenseURL(), Value: p.RightsText()}
+		if id := license.SPDX(); id != "" {
+			dr.Identifier, dr.IdentifierScheme = strings.ToLower(id), "SPDX"
+		}
+		res.Rights = []DataCiteRights{dr}
+	}
+	if p.Description != "" {
+		res.Descriptions = []DataCiteDescription{{Type: "Abstract",
+			Value: p.DescriptionText()}}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,96 @@
+package metadata
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration describe the playback duration in ISO 8601 format, for example
+// PT2H13M5.5S. Days and weeks are supported, years and months are not.
+type Duration string
+
+// UnmarshalYAML implement yaml.Unmarshaler interface.
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	var s string
+	if err := value.Decode(&s); err != nil {
+		return err
+	}
+	if _, err := parseDuration(s); err != nil {
+		return err
+	}
+	*d = Duration(s)
+	return nil
+}
+
+// MarshalYAML implement yaml.Marshaler interface.
+func (d Duration) MarshalYAML() (interface{}, error) {
+	if _, err := parseDuration(string(d)); err != nil {
+		return nil, err
+	}
+	return string(d), nil
+}
+
+// Duration return parsed duration value. Invalid value return zero.
+func (d Duration) Duration() time.Duration {
+	duration, _ := parseDuration(string(d))
+	return duration
+}
+
+// Clock return duration as SMIL clock value (H:MM:SS.fff) used by EPUB
+// media overlays.
+func (d Duration) Clock() string {
+	var duration = d.Duration()
+	var h = int(duration / time.Hour)
+	var m = int(duration % time.Hour / time.Minute)
+	var s = int(duration % time.Minute / time.Second)
+	var clock = fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	if ms := int(duration % time.Second / time.Millisecond); ms > 0 {
+		clock += fmt.Sprintf(".%03d", ms)
+	}
+	return clock
+}
+
+// NewDuration return duration in ISO 8601 format.
+func NewDuration(duration time.Duration) Duration {
+	if duration <= 0 {
+		return "PT0S"
+	}
+	var result = "PT"
+	if h := duration / time.Hour; h > 0 {
+		result += strconv.Itoa(int(h)) + "H"
+	}
+	if m := duration % time.Hour / time.Minute; m > 0 {
+		result += strconv.Itoa(int(m)) + "M"
+	}
+	if s := duration % time.Minute; s > 0 {
+		result += strconv.FormatFloat(s.Seconds(), 'f', -1, 64) + "S"
+	}
+	return Duration(result)
+}
+
+var reDuration = regexp.MustCompile(
+	`^P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+func parseDuration(s string) (time.Duration, error) {
+	var parts = reDuration.FindStringSubmatch(s)
+	if parts == nil || s == "P" || s[len(s)-1] == 'T' {
+		return 0, fmt.Errorf("bad duration %v", s)
+	}
+	var seconds float64
+	for i, unit := range []float64{7 * 24 * 3600, 24 * 3600, 3600, 60, 1} {
+		if parts[i+1] == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(parts[i+1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("bad duration %v", s)
+		}
+		seconds += value * unit
+	}
+	return time.Duration(math.Round(seconds * float64(time.Second))), nil
+}
@@ -0,0 +1,272 @@
+package metadata
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// EndNoteXML is an EndNote XML document with the list of records.
+type EndNoteXML struct {
+	XMLName xml.Name        `xml:"xml"`
+	Records []EndNoteRecord `xml:"records>record"`
+}
+
+// EndNoteRecord is an EndNote XML record.
+type EndNoteRecord struct {
+	RefType        EndNoteRefType       `xml:"ref-type"`
+	Contributors   *EndNoteContributors `xml:"contributors,omitempty"`
+	Title          *EndNoteText         `xml:"titles>title,omitempty"`
+	SecondaryTitle *EndNoteText         `xml:"titles>secondary-title,omitempty"`
+	Volume         *EndNoteText         `xml:"volume,omitempty"`
+	Keywords       *EndNoteKeywords     `xml:"keywords,omitempty"`
+	Dates          *EndNoteDates        `xml:"dates,omitempty"`
+	Publisher      *EndNoteText         `xml:"publisher,omitempty"`
+	ISBN           []EndNoteText        `xml:"isbn,omitempty"`
+	DOI            *EndNoteText         `xml:"electronic-resource-num,omitempty"`
+	Abstract       *EndNoteText         `xml:"abstract,omitempty"`
+	Language       *EndNoteText         `xml:"language,omitempty"`
+}
+
+// EndNoteContributors is an EndNote record contributors.
+type EndNoteContributors struct {
+	Authors           *EndNoteAuthors `xml:"authors,omitempty"`
+	SecondaryAuthors  *EndNoteAuthors `xml:"secondary-authors,omitempty"`
+	TertiaryAuthors   *EndNoteAuthors `xml:"tertiary-authors,omitempty"`
+	SubsidiaryAuthors *EndNoteAuthors `xml:"subsidiary-authors,omitempty"`
+}
+
+// EndNoteAuthors is a list of EndNote authors names.
+type EndNoteAuthors struct {
+	Author []EndNoteText `xml:"author"`
+}
+
+// EndNoteKeywords is a list of EndNote keywords.
+type EndNoteKeywords struct {
+	Keyword []EndNoteText `xml:"keyword"`
+}
+
+// EndNoteDates is an EndNote record dates.
+type EndNoteDates struct {
+	Year     *EndNoteText     `xml:"year,omitempty"`
+	PubDates *EndNotePubDates `xml:"pub-dates,omitempty"`
+}
+
+// EndNotePubDates is a list of EndNote publication dates.
+type EndNotePubDates struct {
+	Date []EndNoteText `xml:"date"`
+}
+
+// EndNoteRefType is an EndNote reference type.
+type EndNoteRefType struct {
+	Name  string `xml:"name,attr,omitempty"`
+	Value int    `xml:",chardata"`
+}
+
+// EndNoteText is an EndNote text value. EndNote writes text inside style
+// elements, but plain text is accepted too.
+type EndNoteText struct {
+	Text  string   `xml:",chardata"`
+	Style []string `xml:"style,omitempty"`
+}
+
+// String return the text value.
+func (t *EndNoteText) String() string {
+	if t == nil {
+		return ""
+	}
+	return strings.TrimSpace(t.Text + strings.Join(t.Style, ""))
+}
+
+// endNoteText return EndNote text or nil for empty string.
+func endNoteText(s string) *EndNoteText {
+	if s == "" {
+		return nil
+	}
+	return &EndNoteText{Text: s}
+}
+
+// endNoteTexts return the list of EndNote text.
+func endNoteTexts(list []string) []EndNoteText {
+	var result = make([]EndNoteText, len(list))
+	for i, s := range list {
+		result[i] = EndNoteText{Text: s}
+	}
+	return result
+}
+
+// endNoteAuthors return EndNote authors or nil for empty list.
+func endNoteAuthors(names []string) *EndNoteAuthors {
+	if len(names) == 0 {
+		return nil
+	}
+	return &EndNoteAuthors{Author: endNoteTexts(names)}
+}
+
+// names return the authors names.
+func (a *EndNoteAuthors) names() []string {
+	if a == nil {
+		return nil
+	}
+	var names []string
+	for _, name := range a.Author {
+		if text := name.String(); text != "" {
+			names = append(names, text)
+		}
+	}
+	return names
+}
+
+// EndNote return publication metadata as EndNote XML record.
+func (p Publication) EndNote() EndNoteRecord {
+	var rt = risTypes[p.risType()]
+	var record = EndNoteRecord{
+		RefType:        EndNoteRefType{Name: rt.name, Value: rt.endNote},
+		Title:          endNoteText(p.citeTitle()),
+		SecondaryTitle: endNoteText(p.BelongsToCollection),
+		Volume:         endNoteText(p.GroupPosition),
+		Publisher:      endNoteText(p.Publisher),
+		Abstract:       endNoteText(p.DescriptionText()),
+		Language:       endNoteText(p.Language),
+	}
+
+	if authors := p.citeAuthors(); len(authors) > 0 {
+		record.Contributors = &EndNoteContributors{
+			Authors:           endNoteAuthors(authors["AU"]),
+			SecondaryAuthors:  endNoteAuthors(authors["A2"]),
+			TertiaryAuthors:   endNoteAuthors(authors["A3"]),
+			SubsidiaryAuthors: endNoteAuthors(authors["A4"]),
+		}
+	}
+	if len(p.Subject) > 0 {
+		record.Keywords = &EndNoteKeywords{Keyword: endNoteTexts(p.Subject)}
+	}
+	if p.Date != "" {
+		record.Dates = &EndNoteDates{Year: endNoteText(reYear.FindString(string(p.Date)))}
+		record.Dates.PubDates = &EndNotePubDates{Date: endNoteTexts([]string{string(p.Date)})}
+	}
+
+	for _, id := range p.Identifier {
+		switch scheme := strings.ToUpper(id.Scheme); {
+		case strings.HasPrefix(scheme, "ISBN"):
+			record.ISBN = append(record.ISBN, EndNoteText{Text: id.Text})
+		case scheme == "DOI" && record.DOI == nil:
+			record.DOI = endNoteText(normalizeDOI(id.Text))
+		}
+	}
+	return record
+}
+
+// WriteEndNote write publications as EndNote XML document.
+func WriteEndNote(w io.Writer, pubs ...*Publication) error {
+	var doc EndNoteXML
+	for _, pub := range pubs {
+		doc.Records = append(doc.Records, pub.EndNote())
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
+
+// ParseEndNote return publications metadata from EndNote XML document.
+func ParseEndNote(data []byte) ([]*Publication, error) {
+	var doc EndNoteXML
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	var pubs = make([]*Publication, len(doc.Records))
+	for i, record := range doc.Records {
+		pubs[i] = record.Publication()
+	}
+	return pubs, nil
+}
+
+// Publication return publication metadata from EndNote record.
+func (r EndNoteRecord) Publication() *Publication {
+	var pub = &Publication{
+		BelongsToCollection: r.SecondaryTitle.String(),
+		GroupPosition:       r.Volume.String(),
+		Publisher:           r.Publisher.String(),
+		Description:         r.Abstract.String(),
+		Language:            r.Language.String(),
+	}
+
+	for code, rt := range risTypes {
+		if rt.endNote == r.RefType.Value && code != "BOOK" {
+			pub.Type = rt.label
+		}
+	}
+	if pub.Type == "" && r.RefType.Value == 0 && r.RefType.Name != "" {
+		pub.Type = r.RefType.Name
+	}
+
+	if c := r.Contributors; c != nil {
+		for _, list := range []struct {
+			authors *EndNoteAuthors
+			tag     string
+		}{
+			{c.Authors, "AU"},
+			{c.SecondaryAuthors, "A2"},
+			{c.TertiaryAuthors, "A3"},
+			{c.SubsidiaryAuthors, "A4"},
+		} {
+			for _, name := range list.authors.names() {
+				var author = citeAuthor(name, citeRoles[list.tag])
+				if list.tag == "AU" {
+					pub.Creator = append(pub.Creator, author)
+				} else {
+					pub.Contributor = append(pub.Contributor, author)
+				}
+			}
+		}
+	}
+
+	if title := r.Title.String(); title != "" {
+		pub.Title = Titles{{Type: "main", Text: title}}
+	}
+
+	if r.Keywords != nil {
+		for _, keyword := range r.Keywords.Keyword {
+			if text := keyword.String(); text != "" {
+				pub.Subject = append(pub.Subject, text)
+			}
+		}
+	}
+
+	if d := r.Dates; d != nil {
+		if d.PubDates != nil {
+			for _, date := range d.PubDates.Date {
+				if text := date.String(); checkDateFormat(text) == nil {
+					pub.Date = Date(text)
+					break
+				}
+			}
+		}
+		if year := d.Year.String(); pub.Date == "" && year != "" {
+			if _, err := strconv.Atoi(year); err == nil {
+				pub.Date = Date(year)
+			}
+		}
+	}
+
+	for _, isbn := range r.ISBN {
+		var text = isbn.String()
+		if text == "" {
+			continue
+		}
+		var scheme = "ISBN-13"
+		if len(strings.ReplaceAll(text, "-", "")) == 10 {
+			scheme = "ISBN-10"
+		}
+		pub.Identifier = append(pub.Identifier, Identifier{Scheme: scheme, Text: text})
+	}
+	if doi := r.DOI.String(); doi != "" {
+		pub.Identifier = append(pub.Identifier, Identifier{Scheme: "DOI", Text: normalizeDOI(doi)})
+	}
+
+	return pub
+}
@@ -0,0 +1,184 @@
+package metadata
+
+import (
+	"encoding/xml"
+	"strings"
+
+	epub "github.com/mdigger/epub3"
+)
+
+// CoverImageID is the manifest item ID of the cover image referenced by
+// EPUB 2 cover meta.
+const CoverImageID = "cover-image"
+
+// OPFMetadata is an OPF package metadata with EPUB 2 attributes. It used
+// for EPUB 2 and hybrid (EPUB 2 attributes with EPUB 3 refinements) output.
+type OPFMetadata struct {
+	XMLName     xml.Name     `xml:"metadata"`
+	DC          string       `xml:"xmlns:dc,attr"`
+	OPF         string       `xml:"xmlns:opf,attr"`
+	Identifier  []OPFElement `xml:"dc:identifier"`
+	Title       []OPFElement `xml:"dc:title"`
+	Language    []OPFElement `xml:"dc:language"`
+	Date        []OPFElement `xml:"dc:date,omitempty"`
+	Creator     []OPFElement `xml:"dc:creator,omitempty"`
+	Contributor []OPFElement `xml:"dc:contributor,omitempty"`
+	Subject     []OPFElement `xml:"dc:subject,omitempty"`
+	Description []OPFElement `xml:"dc:description,omitempty"`
+	Type        []OPFElement `xml:"dc:type,omitempty"`
+	Format      []OPFElement `xml:"dc:format,omitempty"`
+	Publisher   []OPFElement `xml:"dc:publisher,omitempty"`
+	Source      []OPFElement `xml:"dc:source,omitempty"`
+	Relation    []OPFElement `xml:"dc:relation,omitempty"`
+	Coverage    []OPFElement `xml:"dc:coverage,omitempty"`
+	Rights      []OPFElement `xml:"dc:rights,omitempty"`
+	Meta        []OPFMeta    `xml:"meta,omitempty"`
+	Link        []epub.Link  `xml:"link,omitempty"`
+}
+
+// OPFElement is a DCMES element with EPUB 2 opf attributes.
+type OPFElement struct {
+	ID     string `xml:"id,attr,omitempty"`
+	Role   string `xml:"opf:role,attr,omitempty"`
+	FileAs string `xml:"opf:file-as,attr,omitempty"`
+	Scheme string `xml:"opf:scheme,attr,omitempty"`
+	Event  string `xml:"opf:event,attr,omitempty"`
+	Lang   string `xml:"xml:lang,attr,omitempty"`
+	Value  string `xml:",chardata"`
+}
+
+// OPFMeta is a meta element in EPUB 2 (name & content) or EPUB 3 (property)
+// form.
+type OPFMeta struct {
+	Name     string `xml:"name,attr,omitempty"`
+	Content  string `xml:"content,attr,omitempty"`
+	Refines  string `xml:"refines,attr,omitempty"`
+	Property string `xml:"property,attr,omitempty"`
+	Scheme   string `xml:"scheme,attr,omitempty"`
+	ID       string `xml:"id,attr,omitempty"`
+	Lang     string `xml:"xml:lang,attr,omitempty"`
+	Value    string `xml:",chardata"`
+}
+
+// EPUB2 return converted to EPUB 2 OPF metadata: roles, file-as and schemes
+// are defined as opf attributes and no refinements are used.
+func (p Publication) EPUB2() OPFMetadata {
+	return p.opf(false)
+}
+
+// EPUBHybrid return OPF metadata with both EPUB 2 attributes and EPUB 3
+// refinements.
+func (p Publication) EPUBHybrid() OPFMetadata {
+	return p.opf(true)
+}
+
+// opfScheme return identifier scheme name for EPUB 2 opf:scheme attribute.
+func (id Identifier) opfScheme() string {
+	if strings.HasPrefix(strings.ToUpper(id.Scheme), "ISBN") {
+		return "ISBN"
+	}
+	return id.Scheme
+}
+
+func (p Publication) opf(hybrid bool) OPFMetadata {
+	var meta = p.EPUB()
+	var opf = OPFMetadata{
+		DC:  meta.DC,
+		OPF: "http://www.idpf.org/2007/opf",
+	}
+
+	// convert EPUB 3 elements; ids are kept in hybrid mode only
+	convert := func(list []epub.ElementLang) []OPFElement {
+		var result = make([]OPFElement, len(list))
+		for i, el := range list {
+			result[i] = OPFElement{Lang: el.Lang, Value: el.Value}
+			if hybrid {
+				result[i].ID = el.ID
+			}
+		}
+		return result
+	}
+	convertElements := func(list []epub.Element) []OPFElement {
+		var result = make([]OPFElement, len(list))
+		for i, el := range list {
+			result[i] = OPFElement{Value: el.Value}
+		}
+		return result
+	}
+
+	// identifiers always keep ids for package unique-identifier
+	for i, id := range p.Identifier {
+		opf.Identifier = append(opf.Identifier, OPFElement{
+			ID:     EPUBOptions{}.id("id", i, len(p.Identifier)),
+			Scheme: id.opfScheme(),
+			Value:  id.Text,
+		})
+	}
+
+	opf.Title = convert(meta.Title)
+	opf.Language = convertElements(meta.Language)
+
+	// dates
+	if meta.Date != nil {
+		opf.Date = append(opf.Date, OPFElement{Event: "publication", Value: meta.Date.Value})
+	}
+	if p.Modified != "" && !hybrid {
+		opf.Date = append(opf.Date, OPFElement{Event: "modification", Value: string(p.Modified)})
+	}
+
+	// authors
+	decorate := func(list []OPFElement, authors Authors) []OPFElement {
+		for i := range list {
+			list[i].FileAs = authors[i].FileAs
+			if codes := authors[i].Relators(p.Language); len(codes) > 0 {
+				list[i].Role = codes[0]
+			}
+		}
+		return list
+	}
+	opf.Creator = decorate(convert(meta.Creator), p.Creator)
+	opf.Contributor = decorate(convert(meta.Contributor), p.Contributor)
+
+	opf.Subject = convert(meta.Subject)
+	opf.Description = convert(meta.Description)
+	opf.Type = convertElements(meta.Type)
+	opf.Format = convertElements(meta.Format)
+	opf.Publisher = convert(meta.Publisher)
+	opf.Source = convertElements(meta.Source)
+	opf.Relation = convert(meta.Relation)
+	opf.Coverage = convert(meta.Coverage)
+	opf.Rights = convert(meta.Rights)
+
+	// EPUB 3 metas
+	if hybrid {
+		for _, m := range meta.Meta {
+			opf.Meta = append(opf.Meta, OPFMeta{
+				Refines:  m.Refines,
+				Property: m.Property,
+				Scheme:   m.Scheme,
+				ID:       m.ID,
+				Lang:     m.Lang,
+				Value:    m.Value,
+			})
+		}
+		opf.Link = meta.Link
+	}
+
+	// EPUB 2 metas
+	if p.CoverImage != nil {
+		opf.Meta = append(opf.Meta, OPFMeta{Name: "cover", Content: CoverImageID})
+	}
+	if p.Kindle != nil {
+		opf.Meta = append(opf.Meta, p.Kindle.opf()...)
+	}
+	if p.BelongsToCollection != "" {
+		opf.Meta = append(opf.Meta, OPFMeta{
+			Name: "calibre:series", Content: p.BelongsToCollection})
+		if p.GroupPosition != "" {
+			opf.Meta = append(opf.Meta, OPFMeta{
+				Name: "calibre:series_index", Content: p.GroupPosition})
+		}
+	}
+
+	return opf
+}
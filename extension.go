@@ -0,0 +1,170 @@
+package metadata
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	epub "github.com/mdigger/epub3"
+	"gopkg.in/yaml.v3"
+)
+
+// Extension is a typed extension property. Registered extension properties
+// are decoded from the publication YAML and emitted to EPUB metadata.
+type Extension interface {
+	// EPUB return EPUB metadata elements for the property name.
+	EPUB(name string) ([]epub.Meta, []epub.Link)
+}
+
+// ExtensionFactory return a new extension value to decode YAML into.
+type ExtensionFactory func() Extension
+
+// extensions are registered extension properties by prefixed name.
+var extensions = make(map[string]ExtensionFactory)
+
+// RegisterExtension register typed extension property with prefixed name
+// like "schema:accessMode". It should be called on initialization only.
+func RegisterExtension(name string, factory ExtensionFactory) {
+	extensions[name] = factory
+}
+
+func init() {
+	newMetaValues := func() Extension { return new(MetaValues) }
+	for _, name := range []string{
+		"schema:accessMode",
+		"schema:accessModeSufficient",
+		"schema:accessibilityAPI",
+		"schema:accessibilityControl",
+		"schema:accessibilityFeature",
+		"schema:accessibilityHazard",
+		"schema:accessibilitySummary",
+		"dcterms:conformsTo",
+		"a11y:certifiedBy",
+		"a11y:certifierCredential",
+	} {
+		RegisterExtension(name, newMetaValues)
+	}
+	RegisterExtension("a11y:certifierReport", func() Extension { return new(LinkValues) })
+}
+
+// MetaValues is an extension property emitted as EPUB meta element for each
+// value.
+type MetaValues []string
+
+// EPUB implement Extension interface.
+func (v MetaValues) EPUB(name string) ([]epub.Meta, []epub.Link) {
+	var metas = make([]epub.Meta, 0, len(v))
+	for _, value := range v {
+		metas = append(metas, epub.Meta{Property: name, Value: value})
+	}
+	return metas, nil
+}
+
+// MarshalYAML implement yaml.Marshaler interface.
+func (v MetaValues) MarshalYAML() (interface{}, error) {
+	return Strings(v).MarshalYAML()
+}
+
+// UnmarshalYAML implement yaml.Unmarshaler interface.
+func (v *MetaValues) UnmarshalYAML(value *yaml.Node) error {
+	return (*Strings)(v).UnmarshalYAML(value)
+}
+
+// LinkValues is an extension property emitted as EPUB link element with
+// the property name as relation for each URL.
+type LinkValues []string
+
+// EPUB implement Extension interface.
+func (v LinkValues) EPUB(name string) ([]epub.Meta, []epub.Link) {
+	var links = make([]epub.Link, 0, len(v))
+	for _, href := range v {
+		links = append(links, epub.Link{Rel: name, Href: href})
+	}
+	return nil, links
+}
+
+// MarshalYAML implement yaml.Marshaler interface.
+func (v LinkValues) MarshalYAML() (interface{}, error) {
+	return Strings(v).MarshalYAML()
+}
+
+// UnmarshalYAML implement yaml.Unmarshaler interface.
+func (v *LinkValues) UnmarshalYAML(value *yaml.Node) error {
+	return (*Strings)(v).UnmarshalYAML(value)
+}
+
+// decodeExtensions move registered extension properties from Properties to
+// Extensions decoded from YAML document data.
+func (p *Publication) decodeExtensions(data []byte) error {
+	var nodes map[string]yaml.Node
+	for name := range p.Properties {
+		factory, ok := extensions[name]
+		if !ok {
+			continue
+		}
+		if nodes == nil {
+			if err := yaml.Unmarshal(data, &nodes); err != nil {
+				return err
+			}
+		}
+		var node = nodes[name]
+		var ext = factory()
+		if err := node.Decode(ext); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if p.Extensions == nil {
+			p.Extensions = make(map[string]Extension)
+		}
+		p.Extensions[name] = ext
+		delete(p.Properties, name)
+	}
+	return nil
+}
+
+// privatePrefixes are the property prefixes of other formats which are not
+// passed to EPUB metadata.
+var privatePrefixes = map[string]bool{"marc": true, "comicinfo": true}
+
+var rePropertyName = regexp.MustCompile(`^([a-z][a-z0-9_-]*):[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// extensionsEPUB return EPUB metadata elements for typed extensions and
+// prefixed properties with simple values.
+func (p Publication) extensionsEPUB() (metas []epub.Meta, links []epub.Link) {
+	var names = make([]string, 0, len(p.Extensions))
+	for name := range p.Extensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		m, l := p.Extensions[name].EPUB(name)
+		metas, links = append(metas, m...), append(links, l...)
+	}
+
+	names = names[:0]
+	for name := range p.Properties {
+		if match := rePropertyName.FindStringSubmatch(name); match != nil && !privatePrefixes[match[1]] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var values []interface{}
+		switch value := p.Properties[name].(type) {
+		case []interface{}:
+			values = value
+		case []string:
+			for _, item := range value {
+				values = append(values, item)
+			}
+		default:
+			values = []interface{}{value}
+		}
+		for _, value := range values {
+			switch value.(type) {
+			case string, bool, int, int64, uint64, float64:
+				metas = append(metas, epub.Meta{Property: name, Value: fmt.Sprint(value)})
+			}
+		}
+	}
+	return metas, links
+}
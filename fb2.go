@@ -0,0 +1,539 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// FB2Description is a FictionBook 2 description element.
+//
+// http://www.fictionbook.org/index.php/Eng:XML_Schema_Fictionbook_2.1
+type FB2Description struct {
+	XMLName      xml.Name         `xml:"description"`
+	TitleInfo    FB2TitleInfo     `xml:"title-info"`
+	DocumentInfo *FB2DocumentInfo `xml:"document-info,omitempty"`
+	PublishInfo  *FB2PublishInfo  `xml:"publish-info,omitempty"`
+}
+
+// FB2TitleInfo describe the book.
+type FB2TitleInfo struct {
+	Genre      []string       `xml:"genre"`
+	Author     []FB2Author    `xml:"author"`
+	BookTitle  string         `xml:"book-title"`
+	Annotation *FB2Annotation `xml:"annotation,omitempty"`
+	Keywords   string         `xml:"keywords,omitempty"`
+	Date       *FB2Date       `xml:"date,omitempty"`
+	Lang       string         `xml:"lang"`
+	Translator []FB2Author    `xml:"translator,omitempty"`
+	Sequence   []FB2Sequence  `xml:"sequence,omitempty"`
+}
+
+// FB2Author is a FictionBook author or translator.
+type FB2Author struct {
+	FirstName  string `xml:"first-name,omitempty"`
+	MiddleName string `xml:"middle-name,omitempty"`
+	LastName   string `xml:"last-name,omitempty"`
+	Nickname   string `xml:"nickname,omitempty"`
+	HomePage   string `xml:"home-page,omitempty"`
+	Email      string `xml:"email,omitempty"`
+	ID         string `xml:"id,omitempty"`
+}
+
+// FB2Annotation is a book annotation as the list of paragraphs.
+type FB2Annotation struct {
+	Paragraphs []FB2Paragraph `xml:"p"`
+}
+
+// FB2Paragraph is a paragraph text. The text of inline elements like
+// emphasis, strong or links is included on decoding.
+type FB2Paragraph string
+
+// UnmarshalXML implement xml.Unmarshaler interface.
+func (p *FB2Paragraph) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	var text strings.Builder
+	for depth := 1; depth > 0; {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			text.Write(token)
+		}
+	}
+	*p = FB2Paragraph(text.String())
+	return nil
+}
+
+// FB2Date is a date in human readable form with optional machine readable
+// value.
+type FB2Date struct {
+	Value string `xml:"value,attr,omitempty"`
+	Text  string `xml:",chardata"`
+}
+
+// FB2Sequence is a book series with the number in it.
+type FB2Sequence struct {
+	Name   string `xml:"name,attr"`
+	Number string `xml:"number,attr,omitempty"`
+}
+
+// FB2DocumentInfo describe the FictionBook document.
+type FB2DocumentInfo struct {
+	Author      []FB2Author `xml:"author"`
+	ProgramUsed string      `xml:"program-used,omitempty"`
+	Date        *FB2Date    `xml:"date"`
+	ID          string      `xml:"id"`
+	Version     string      `xml:"version"`
+}
+
+// FB2PublishInfo describe the paper publication of the book.
+type FB2PublishInfo struct {
+	BookName  string        `xml:"book-name,omitempty"`
+	Publisher string        `xml:"publisher,omitempty"`
+	City      string        `xml:"city,omitempty"`
+	Year      string        `xml:"year,omitempty"`
+	ISBN      string        `xml:"isbn,omitempty"`
+	Sequence  []FB2Sequence `xml:"sequence,omitempty"`
+}
+
+// FB2Genres is a list of FictionBook genre codes with english names.
+var FB2Genres = map[string]string{
+	"sf_history":         "Alternative History",
+	"sf_action":          "Action SF",
+	"sf_epic":            "Epic SF",
+	"sf_heroic":          "Heroic SF",
+	"sf_detective":       "Detective SF",
+	"sf_cyberpunk":       "Cyberpunk",
+	"sf_space":           "Space SF",
+	"sf_social":          "Social SF",
+	"sf_horror":          "Horror & Mystic",
+	"sf_humor":           "Humor SF",
+	"sf_fantasy":         "Fantasy",
+	"sf":                 "Science Fiction",
+	"det_classic":        "Classical Detective",
+	"det_police":         "Police Stories",
+	"det_action":         "Action Detective",
+	"det_irony":          "Ironical Detective",
+	"det_history":        "Historical Detective",
+	"det_espionage":      "Espionage Detective",
+	"det_crime":          "Crime Detective",
+	"det_political":      "Political Detective",
+	"det_maniac":         "Maniacs",
+	"det_hard":           "Hard-boiled Detective",
+	"thriller":           "Thrillers",
+	"detective":          "Detective",
+	"prose_classic":      "Classics Prose",
+	"prose_history":      "Historical Prose",
+	"prose_contemporary": "Contemporary Prose",
+	"prose_counter":      "Counterculture",
+	"prose_rus_classic":  "Russian Classics",
+	"prose_su_classics":  "Soviet Classics",
+	"love_contemporary":  "Contemporary Romance",
+	"love_history":       "Historical Romance",
+	"love_detective":     "Detective Romance",
+	"love_short":         "Short Romance",
+	"love_erotica":       "Erotica",
+	"adv_western":        "Western",
+	"adv_history":        "History Adventure",
+	"adv_indian":         "Indians",
+	"adv_maritime":       "Maritime Fiction",
+	"adv_geo":            "Travel & Geography",
+	"adv_animal":         "Nature & Animals",
+	"adventure":          "Adventure",
+	"child_tale":         "Fairy Tales",
+	"child_verse":        "Children's Verses",
+	"child_prose":        "Children's Prose",
+	"child_sf":           "Children's SF",
+	"child_det":          "Children's Action",
+	"child_adv":          "Children's Adventures",
+	"child_education":    "Children's Education",
+	"children":           "Children's",
+	"poetry":             "Poetry",
+	"dramaturgy":         "Dramaturgy",
+	"antique_ant":        "Antique",
+	"antique_european":   "European Literature",
+	"antique_russian":    "Old Russian Literature",
+	"antique_east":       "Old East Literature",
+	"antique_myths":      "Myths. Legends. Epos",
+	"antique":            "Other Antique",
+	"sci_history":        "History",
+	"sci_psychology":     "Psychology",
+	"sci_culture":        "Cultural Science",
+	"sci_religion":       "Religious Studies",
+	"sci_philosophy":     "Philosophy",
+	"sci_politics":       "Politics",
+	"sci_business":       "Business Literature",
+	"sci_juris":          "Jurisprudence",
+	"sci_linguistic":     "Linguistics",
+	"sci_medicine":       "Medicine",
+	"sci_phys":           "Physics",
+	"sci_math":           "Mathematics",
+	"sci_chem":           "Chemistry",
+	"sci_biology":        "Biology",
+	"sci_tech":           "Technical",
+	"science":            "Other Science",
+	"comp_www":           "Internet",
+	"comp_programming":   "Programming",
+	"comp_hard":          "Hardware",
+	"comp_soft":          "Software",
+	"comp_db":            "Databases",
+	"comp_osnet":         "OS & Networking",
+	"computers":          "Other Computers",
+	"ref_encyc":          "Encyclopedias",
+	"ref_dict":           "Dictionaries",
+	"ref_ref":            "Reference",
+	"ref_guide":          "Guidebooks",
+	"reference":          "Other Reference",
+	"nonf_biography":     "Biography & Memoirs",
+	"nonf_publicism":     "Publicism",
+	"nonf_criticism":     "Criticism",
+	"design":             "Art & Design",
+	"nonfiction":         "Other Nonfiction",
+	"religion_rel":       "Religion",
+	"religion_esoterics": "Esoterics",
+	"religion_self":      "Self-improvement",
+	"religion":           "Other Religion",
+	"humor_anecdote":     "Anecdote",
+	"humor_prose":        "Humorous Prose",
+	"humor_verse":        "Humorous Verses",
+	"humor":              "Other Humor",
+	"home_cooking":       "Cooking",
+	"home_pets":          "Pets",
+	"home_crafts":        "Hobbies & Crafts",
+	"home_entertain":     "Entertaining",
+	"home_health":        "Health",
+	"home_garden":        "Garden",
+	"home_diy":           "Do It Yourself",
+	"home_sport":         "Sports",
+	"home_sex":           "Erotica & Sex",
+	"home":               "Other Home",
+}
+
+// FB2Genre return FictionBook genre code for subject. The subject may be
+// a genre code or its english name.
+func FB2Genre(subject string) (code string, ok bool) {
+	subject = strings.TrimSpace(subject)
+	if _, ok := FB2Genres[strings.ToLower(subject)]; ok {
+		return strings.ToLower(subject), true
+	}
+	for code, name := range FB2Genres {
+		if strings.EqualFold(name, subject) {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+// fb2Author return FictionBook author from Author. The name parts are
+// taken from FileAs in "Last, First Middle" form or from the name.
+func fb2Author(author Author) FB2Author {
+	var result FB2Author
+	if i := strings.Index(author.FileAs, ","); i > 0 {
+		result.LastName = strings.TrimSpace(author.FileAs[:i])
+		names := strings.Fields(author.FileAs[i+1:])
+		if len(names) > 0 {
+			result.FirstName = names[0]
+			result.MiddleName = strings.Join(names[1:], " ")
+		}
+		return result
+	}
+
+	names := strings.Fields(author.Text)
+	switch len(names) {
+	case 0:
+	case 1:
+		result.Nickname = names[0]
+	default:
+		result.FirstName = names[0]
+		result.MiddleName = strings.Join(names[1:len(names)-1], " ")
+		result.LastName = names[len(names)-1]
+	}
+	return result
+}
+
+// Author return Author with name and FileAs from FictionBook author.
+func (a FB2Author) Author() Author {
+	var names []string
+	for _, name := range []string{a.FirstName, a.MiddleName, a.LastName} {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return Author{Text: strings.TrimSpace(a.Nickname)}
+	}
+
+	var author = Author{Text: strings.Join(names, " ")}
+	if last := strings.TrimSpace(a.LastName); last != "" && len(names) > 1 {
+		author.FileAs = last + ", " + strings.Join(names[:len(names)-1], " ")
+	}
+	return author
+}
+
+var reParagraphs = regexp.MustCompile(`\n\s*\n`)
+
+// FB2 return publication metadata as FictionBook 2 description.
+func (p Publication) FB2() *FB2Description {
+	var d = &FB2Description{
+		TitleInfo: FB2TitleInfo{
+			BookTitle: p.Title.Main(),
+			Lang:      p.Language,
+		},
+	}
+	var ti = &d.TitleInfo
+
+	// genres & keywords
+	var keywords []string
+	for _, subject := range p.Subject {
+		if code, ok := FB2Genre(subject); ok {
+			ti.Genre = append(ti.Genre, code)
+		} else {
+			keywords = append(keywords, subject)
+		}
+	}
+	ti.Keywords = strings.Join(keywords, ", ")
+
+	// authors
+	for _, author := range p.Creator.Sorted() {
+		ti.Author = append(ti.Author, fb2Author(author))
+	}
+	for _, author := range p.Contributor.Sorted() {
+		for _, code := range author.Relators(p.Language) {
+			if code == "trl" {
+				ti.Translator = append(ti.Translator, fb2Author(author))
+				break
+			}
+		}
+	}
+
+	// annotation
+	if description := p.DescriptionText(); description != "" {
+		var annotation = new(FB2Annotation)
+		for _, par := range reParagraphs.Split(description, -1) {
+			annotation.Paragraphs = append(annotation.Paragraphs,
+				FB2Paragraph(strings.Join(strings.Fields(par), " ")))
+		}
+		ti.Annotation = annotation
+	}
+
+	if len(p.Date) >= 4 {
+		ti.Date = &FB2Date{Text: string(p.Date)[:4]}
+		if len(p.Date) >= 10 {
+			ti.Date.Value = string(p.Date)[:10]
+		}
+	}
+
+	if p.BelongsToCollection != "" {
+		ti.Sequence = []FB2Sequence{{
+			Name: p.BelongsToCollection, Number: p.GroupPosition}}
+	}
+
+	// publish info
+	var isbn string
+	for _, id := range p.Identifier {
+		if strings.HasPrefix(strings.ToUpper(id.Scheme), "ISBN") {
+			isbn = strings.TrimPrefix(id.Text, "urn:isbn:")
+			break
+		}
+	}
+	if p.Publisher != "" || isbn != "" {
+		d.PublishInfo = &FB2PublishInfo{
+			BookName:  ti.BookTitle,
+			Publisher: p.Publisher,
+			ISBN:      isbn,
+		}
+		if len(p.Date) >= 4 {
+			d.PublishInfo.Year = string(p.Date)[:4]
+		}
+	}
+
+	// document info
+	for _, id := range p.Identifier {
+		if strings.HasPrefix(strings.ToUpper(id.Scheme), "ISBN") {
+			continue
+		}
+		d.DocumentInfo = &FB2DocumentInfo{
+			ID:      strings.TrimPrefix(id.Text, "urn:uuid:"),
+			Version: string(p.Version),
+		}
+		if modified := string(p.Modified); modified != "" {
+			if len(modified) > 10 {
+				modified = modified[:10]
+			}
+			d.DocumentInfo.Date = &FB2Date{Text: modified}
+			if len(modified) == 10 {
+				d.DocumentInfo.Date.Value = modified
+			}
+		}
+		break
+	}
+
+	return d
+}
+
+// ParseFB2 return publication metadata from FictionBook 2 file data. Only
+// the description element is decoded.
+func ParseFB2(data []byte) (*Publication, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.CharsetReader = charsetReader // windows-1251 & koi8-r are common
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("FB2 description not found")
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "description" {
+			continue
+		}
+		start.Name.Space = "" // ignore FictionBook namespace
+		var d FB2Description
+		if err := dec.DecodeElement(&d, &start); err != nil {
+			return nil, err
+		}
+		return d.Publication(), nil
+	}
+}
+
+// LoadFB2 return publication metadata from FictionBook 2 file.
+func LoadFB2(filename string) (*Publication, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFB2(data)
+}
+
+// Publication return publication metadata from FictionBook description.
+func (d FB2Description) Publication() *Publication {
+	var ti = d.TitleInfo
+	var pub = &Publication{
+		Language: strings.TrimSpace(ti.Lang),
+	}
+
+	if title := strings.TrimSpace(ti.BookTitle); title != "" {
+		pub.Title = Titles{{Type: "main", Text: title}}
+	}
+
+	for _, genre := range ti.Genre {
+		if genre = strings.TrimSpace(genre); genre != "" {
+			if name, ok := FB2Genres[genre]; ok {
+				genre = name
+			}
+			pub.Subject = append(pub.Subject, genre)
+		}
+	}
+	for _, keyword := range strings.Split(ti.Keywords, ",") {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			pub.Subject = append(pub.Subject, keyword)
+		}
+	}
+
+	for _, a := range ti.Author {
+		if author := a.Author(); author.Text != "" {
+			pub.Creator = append(pub.Creator, author)
+		}
+	}
+	for _, a := range ti.Translator {
+		if author := a.Author(); author.Text != "" {
+			author.Role = Strings{"trl"}
+			pub.Contributor = append(pub.Contributor, author)
+		}
+	}
+
+	if ti.Annotation != nil {
+		var pars []string
+		for _, par := range ti.Annotation.Paragraphs {
+			if par := strings.Join(strings.Fields(string(par)), " "); par != "" {
+				pars = append(pars, par)
+			}
+		}
+		pub.Description = strings.Join(pars, "\n\n")
+	}
+
+	if ti.Date != nil {
+		pub.Date = ti.Date.date()
+	}
+
+	var sequences = ti.Sequence
+	if d.PublishInfo != nil {
+		var pi = d.PublishInfo
+		pub.Publisher = strings.TrimSpace(pi.Publisher)
+		if isbn := strings.TrimSpace(pi.ISBN); isbn != "" {
+			var id = Identifier{Scheme: "ISBN-13", Text: isbn}
+			if len(strings.ReplaceAll(isbn, "-", "")) == 10 {
+				id.Scheme = "ISBN-10"
+			}
+			pub.Identifier = append(pub.Identifier, id)
+		}
+		if pub.Date == "" {
+			if year := reYear.FindString(pi.Year); year != "" {
+				pub.Date = Date(year)
+			}
+		}
+		sequences = append(sequences, pi.Sequence...)
+	}
+	for _, seq := range sequences {
+		if name := strings.TrimSpace(seq.Name); name != "" {
+			pub.BelongsToCollection = name
+			pub.GroupPosition = strings.TrimSpace(seq.Number)
+			break
+		}
+	}
+
+	if d.DocumentInfo != nil {
+		var di = d.DocumentInfo
+		if text := strings.TrimSpace(di.ID); text != "" {
+			var id = Identifier{Text: text}
+			id.detectScheme()
+			pub.Identifier = append(pub.Identifier, id)
+		}
+		pub.Version = fb2Version(di.Version)
+		if di.Date != nil {
+			pub.Modified = di.Date.date()
+		}
+	}
+
+	return pub
+}
+
+// date return publication date from FictionBook date.
+func (d FB2Date) date() Date {
+	for _, value := range []string{d.Value, strings.TrimSpace(d.Text)} {
+		if value != "" && checkDateFormat(value) == nil {
+			return Date(value)
+		}
+	}
+	if year := reYear.FindString(d.Text); year != "" {
+		return Date(year)
+	}
+	return ""
+}
+
+// fb2Version return the document version in publication version format:
+// "1.1" is converted to "1.1.0".
+func fb2Version(v string) Version {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return ""
+	}
+	for parts := strings.Count(v, ".") + 1; parts < 3; parts++ {
+		v += ".0"
+	}
+	if checkVersionFormat(v) != nil {
+		return ""
+	}
+	return Version(v)
+}
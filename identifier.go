@@ -1,37 +1,40 @@
 package metadata
 
 import (
-	"encoding/hex"
 	"fmt"
 	"strings"
 
-	epub "github.com/mdigger/epub3"
 	"gopkg.in/yaml.v3"
 )
 
 // Identifier of publication.
 //
 // Valid values for scheme are ISBN-10, GTIN-13, UPC, ISMN-10, DOI, LCCN,
-// GTIN-14, ISBN-13, Legal deposit number, URN, OCLC, ISMN-13, ISBN-A, JP, OLCC.
+// GTIN-14, ISBN-13, Legal deposit number, URN, OCLC, ISMN-13, ISBN-A, JP, OLCC,
+// UUID, ISSN, ARK, Handle, URL.
+// If scheme is not defined, it is detected by the text (see DetectScheme).
+//
+// Primary mark the unique identifier of publication. If no identifier is
+// marked, the first UUID or the first identifier is primary.
 type Identifier struct {
-	Scheme string `yaml:",omitempty"`
-	Text   string `yaml:"text"`
+	Scheme  string `yaml:",omitempty"`
+	Text    string `yaml:"text"`
+	Primary bool   `yaml:"primary,omitempty"`
 }
 
 type idType Identifier // alias
 
 // MarshalYAML implement yaml.Marshaler interface.
 func (id Identifier) MarshalYAML() (interface{}, error) {
-	if id.Scheme == "" || id.Scheme == "UUID" {
-		// if !strings.HasPrefix(id.Text, "urn:uuid:") {
-		// 	return fmt.Sprintf("urn:uuid:%v", id.Text), nil
-		// }
+	if (id.Scheme == "" || id.Scheme == "UUID") && !id.Primary {
 		return id.Text, nil // only id
 	}
 	return (idType)(id), nil // as is
 }
 
-// Scheme to Onix CodeList 5 mapper.
+// Scheme to Onix CodeList 5 mapper. CodeList 5 has no codes for ISSN (it
+// is a series identifier, CodeList 13), Handle and URL, so such identifiers
+// and other not mapped schemes are typed as "01" proprietary.
 // https://onix-codelists.io/codelist/5
 var SchemeToOnix = map[string]string{
 	"ISBN-10":              "02",
@@ -49,6 +52,7 @@ var SchemeToOnix = map[string]string{
 	"ISBN-A":               "26",
 	"JP":                   "27",
 	"OLCC":                 "28",
+	"ARK":                  "35",
 }
 
 // Onix return string with Onix CodeList 5: Product identifier type
@@ -71,33 +75,10 @@ func (id *Identifier) UnmarshalYAML(value *yaml.Node) error {
 	default:
 		return fmt.Errorf("unsupported identifier type: %v", value.Kind)
 	}
-	if id.Scheme == "" {
-		if strings.HasPrefix(id.Text, "urn:uuid:") {
-			id.Scheme = "UUID"
-			// (*id).Text = strings.TrimPrefix(id.Text, "urn:uuid:")
-		} else if strings.HasPrefix(id.Text, "urn:isbn:") {
-			id.Scheme = "ISBN"
-			// (*id).Text = strings.TrimPrefix(id.Text, "urn:isbn:")
-		} else if strings.HasPrefix(id.Text, "doi:") {
-			id.Scheme = "DOI"
-			// (*id).Text = strings.TrimPrefix(id.Text, "doi:")
-		} else {
-			// try as uuid
-			id.Scheme = "UUID"
-			// check uid format
-			var text = id.Text
-			for _, byteGroup := range []int{8, 4, 4, 4, 12} {
-				if text[0] == '-' {
-					text = text[1:]
-				}
-				if _, err := hex.DecodeString(text[:byteGroup]); err != nil {
-					id.Scheme = ""
-					break
-				}
-				text = text[byteGroup:]
-			}
-		}
+	if strings.TrimSpace(id.Text) == "" {
+		return fmt.Errorf("line %d: empty identifier", value.Line)
 	}
+	id.detectScheme()
 	return nil
 }
 
@@ -108,10 +89,7 @@ type Identifiers []Identifier
 func (ids Identifiers) MarshalYAML() (interface{}, error) {
 	switch l := len(ids); l {
 	case 0:
-		return &yaml.Node{
-			Kind:        yaml.ScalarNode,
-			LineComment: epub.NewUUID(),
-		}, nil
+		return &yaml.Node{Kind: yaml.ScalarNode}, nil // see Publication.MarshalYAML
 	case 1:
 		return ids[0], nil
 	default:
@@ -119,10 +97,41 @@ func (ids Identifiers) MarshalYAML() (interface{}, error) {
 	}
 }
 
+// Primary return the index of the primary identifier: the first marked as
+// primary, the first UUID or the first one. Return -1 for empty list.
+func (ids Identifiers) Primary() int {
+	for i, id := range ids {
+		if id.Primary {
+			return i
+		}
+	}
+	for i, id := range ids {
+		if strings.EqualFold(id.Scheme, "UUID") {
+			return i
+		}
+	}
+	if len(ids) == 0 {
+		return -1
+	}
+	return 0
+}
+
+// primary return the primary identifier and false for empty list.
+func (ids Identifiers) primary() (Identifier, bool) {
+	if i := ids.Primary(); i >= 0 {
+		return ids[i], true
+	}
+	return Identifier{}, false
+}
+
 // UnmarshalYAML implement yaml.Unmarshaler interface.
 func (ids *Identifiers) UnmarshalYAML(value *yaml.Node) error {
 	switch value.Kind {
 	case yaml.ScalarNode:
+		if value.Tag == "!!null" {
+			*ids = nil // not defined
+			return nil
+		}
 		var id Identifier
 		if err := value.Decode(&id); err != nil {
 			return err
@@ -0,0 +1,407 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// MARCRecord is a MARC 21 bibliographic record.
+//
+// https://www.loc.gov/marc/bibliographic/
+type MARCRecord struct {
+	XMLName       xml.Name           `xml:"http://www.loc.gov/MARC21/slim record"`
+	Leader        string             `xml:"leader"`
+	ControlFields []MARCControlField `xml:"controlfield"`
+	DataFields    []MARCDataField    `xml:"datafield"`
+}
+
+// MARCControlField is a MARC 21 control field (tags 001-009).
+type MARCControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+// MARCDataField is a MARC 21 variable data field with indicators and
+// subfields.
+type MARCDataField struct {
+	Tag       string         `xml:"tag,attr"`
+	Ind1      string         `xml:"ind1,attr"`
+	Ind2      string         `xml:"ind2,attr"`
+	Subfields []MARCSubfield `xml:"subfield"`
+}
+
+// MARCSubfield is a MARC 21 data field subfield.
+type MARCSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+// MARCCollection is a MARCXML collection of records.
+type MARCCollection struct {
+	XMLName xml.Name      `xml:"http://www.loc.gov/MARC21/slim collection"`
+	Records []*MARCRecord `xml:"record"`
+}
+
+// MARCLanguages maps ISO 639-1 language codes to MARC Code List for
+// Languages.
+// https://www.loc.gov/marc/languages/
+var MARCLanguages = map[string]string{
+	"ar": "ara",
+	"be": "bel",
+	"bg": "bul",
+	"cs": "cze",
+	"da": "dan",
+	"de": "ger",
+	"el": "gre",
+	"en": "eng",
+	"es": "spa",
+	"et": "est",
+	"fi": "fin",
+	"fr": "fre",
+	"he": "heb",
+	"hu": "hun",
+	"it": "ita",
+	"ja": "jpn",
+	"kk": "kaz",
+	"ko": "kor",
+	"lt": "lit",
+	"lv": "lav",
+	"nl": "dut",
+	"no": "nor",
+	"pl": "pol",
+	"pt": "por",
+	"ro": "rum",
+	"ru": "rus",
+	"sk": "slo",
+	"sr": "srp",
+	"sv": "swe",
+	"tr": "tur",
+	"uk": "ukr",
+	"zh": "chi",
+}
+
+// marcLanguage return MARC language code for publication language.
+func marcLanguage(lang string) string {
+	lang = langTag(lang)
+	if code, ok := MARCLanguages[lang]; ok {
+		return code
+	}
+	if len(lang) == 3 {
+		return lang
+	}
+	return "und"
+}
+
+// Field return all data fields with tag.
+func (r *MARCRecord) Field(tag string) []MARCDataField {
+	var fields []MARCDataField
+	for _, field := range r.DataFields {
+		if field.Tag == tag {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// Control return the value of control field with tag.
+func (r *MARCRecord) Control(tag string) string {
+	for _, field := range r.ControlFields {
+		if field.Tag == tag {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// Subfield return the first value of subfield with code.
+func (f MARCDataField) Subfield(code string) string {
+	for _, sub := range f.Subfields {
+		if sub.Code == code {
+			return sub.Value
+		}
+	}
+	return ""
+}
+
+// add append data field with not empty subfields.
+func (r *MARCRecord) add(tag, ind1, ind2 string, subfields ...MARCSubfield) {
+	var list = make([]MARCSubfield, 0, len(subfields))
+	for _, sub := range subfields {
+		if sub.Value != "" {
+			list = append(list, sub)
+		}
+	}
+	if len(list) == 0 {
+		return
+	}
+	r.DataFields = append(r.DataFields, MARCDataField{
+		Tag: tag, Ind1: ind1, Ind2: ind2, Subfields: list})
+}
+
+// MARC21 return publication metadata as MARC 21 bibliographic record.
+func (p Publication) MARC21() *MARCRecord {
+	var r = &MARCRecord{
+		Leader: "00000nam a2200000 i 4500",
+	}
+
+	// control fields
+	if id, ok := p.Identifier.primary(); ok {
+		r.ControlFields = append(r.ControlFields,
+			MARCControlField{Tag: "001", Value: id.Text})
+	}
+	r.ControlFields = append(r.ControlFields,
+		MARCControlField{Tag: "007", Value: "cr |||||||||||"},
+		MARCControlField{Tag: "008", Value: p.marc008(p.marcEntered())})
+
+	// identifiers
+	for _, id := range p.Identifier {
+		text := id.Text
+		switch scheme := strings.ToUpper(id.Scheme); {
+		case strings.HasPrefix(scheme, "ISBN"):
+			text = strings.TrimPrefix(text, "urn:isbn:")
+			text = strings.NewReplacer("-", "", " ", "").Replace(text)
+			r.add("020", " ", " ", MARCSubfield{"a", text})
+		case scheme == "DOI":
+			text = strings.TrimPrefix(text, "doi:")
+			r.add("024", "7", " ", MARCSubfield{"a", text}, MARCSubfield{"2", "doi"})
+		case scheme == "UUID":
+			text = strings.TrimPrefix(text, "urn:uuid:")
+			r.add("024", "7", " ", MARCSubfield{"a", text}, MARCSubfield{"2", "uuid"})
+		case scheme == "UPC":
+			r.add("024", "1", " ", MARCSubfield{"a", text})
+		case strings.HasPrefix(scheme, "ISMN"):
+			r.add("024", "2", " ", MARCSubfield{"a", text})
+		case strings.HasPrefix(scheme, "GTIN"):
+			r.add("024", "3", " ", MARCSubfield{"a", text})
+		default:
+			r.add("024", "8", " ", MARCSubfield{"a", text})
+		}
+	}
+
+	// main entry & added entries
+	var authors = append(p.Creator.Sorted(), p.Contributor.Sorted()...)
+	for i, author := range authors {
+		var tag = "700"
+		if i == 0 && len(p.Creator) > 0 {
+			tag = "100"
+		}
+		var name, ind1 = author.Text, "0"
+		if author.FileAs != "" {
+			name = author.FileAs
+		}
+		if strings.Contains(name, ",") {
+			ind1 = "1" // surname
+		}
+		var subfields = []MARCSubfield{{"a", name}}
+		codes := author.Relators(p.Language)
+		for _, code := range codes {
+			subfields = append(subfields, MARCSubfield{"e", MARCLabels[code]})
+		}
+		for _, code := range codes {
+			subfields = append(subfields, MARCSubfield{"4", code})
+		}
+		r.add(tag, ind1, " ", subfields...)
+	}
+
+	// titles
+	var main, subtitle = p.Title.Main(), p.Title.Subtitle()
+	if main != "" {
+		var responsibility = make([]string, 0, len(p.Creator))
+		for _, author := range p.Creator.Sorted() {
+			responsibility = append(responsibility, author.Text)
+		}
+		var ind1 = "0"
+		if len(p.Creator) > 0 {
+			ind1 = "1"
+		}
+		// ISBD punctuation
+		var a, b, c = main, subtitle, strings.Join(responsibility, ", ")
+		if b != "" {
+			a += " :"
+		}
+		if c != "" {
+			if b != "" {
+				b += " /"
+			} else {
+				a += " /"
+			}
+		}
+		r.add("245", ind1, "0",
+			MARCSubfield{"a", a}, MARCSubfield{"b", b}, MARCSubfield{"c", c})
+	}
+	for _, title := range p.Title {
+		switch title.Type {
+		case "main", "subtitle":
+		case "edition":
+			r.add("250", " ", " ", MARCSubfield{"a", title.Text})
+		default:
+			r.add("246", "3", " ", MARCSubfield{"a", title.Text})
+		}
+	}
+
+	// publication
+	var year string
+	if len(p.Date) >= 4 {
+		year = string(p.Date)[:4]
+	}
+	r.add("264", " ", "1",
+		MARCSubfield{"b", p.Publisher}, MARCSubfield{"c", year})
+
+	// series
+	r.add("490", "0", " ",
+		MARCSubfield{"a", p.BelongsToCollection}, MARCSubfield{"v", p.GroupPosition})
+
+	// notes: summary paragraphs as repeated fields
+	for _, par := range reParagraphs.Split(p.DescriptionText(), -1) {
+		r.add("520", " ", " ", MARCSubfield{"a", strings.Join(strings.Fields(par), " ")})
+	}
+	r.add("540", " ", " ", MARCSubfield{"a", p.RightsText()},
+		MARCSubfield{"u", p.license().LicenseURL()})
+
+	// subjects
+	for _, subject := range p.Subject {
+		r.add("650", " ", "4", MARCSubfield{"a", subject})
+	}
+
+	return r
+}
+
+// marcEntered return the date entered on file: the modification or
+// publication date, or the current time if both are unknown.
+func (p Publication) marcEntered() time.Time {
+	for _, date := range []Date{p.Modified, p.Date} {
+		if t, ok := date.Time(); ok {
+			return t
+		}
+	}
+	return time.Now()
+}
+
+// marc008 return fixed-length data elements for books. The record created
+// time is used as the date entered on file.
+func (p Publication) marc008(created time.Time) string {
+	var field = []byte(strings.Repeat(" ", 40))
+	copy(field[0:], created.UTC().Format("060102")) // date entered on file
+	var date = string(p.Date)
+	if len(date) >= 4 {
+		field[6] = 's' // single known date
+		copy(field[7:], date[:4])
+	} else {
+		field[6] = 'n' // dates unknown
+		copy(field[7:], "uuuu")
+	}
+	copy(field[15:], "xx ") // place of publication
+	field[23] = 'o'         // form of item: online
+	copy(field[29:], "000") // conference, festschrift, index
+	field[33] = '|'         // literary form: no attempt to code
+	copy(field[35:], marcLanguage(p.Language))
+	field[39] = 'd' // cataloging source: other
+	return string(field)
+}
+
+// ISO 2709 delimiters.
+const (
+	marcSubfieldDelimiter = 0x1F
+	marcFieldTerminator   = 0x1E
+	marcRecordTerminator  = 0x1D
+)
+
+// ISO2709 return record encoded in MARC 21 exchange format (ISO 2709).
+func (r *MARCRecord) ISO2709() ([]byte, error) {
+	var (
+		directory bytes.Buffer
+		data      bytes.Buffer
+	)
+	addField := func(tag string, value []byte) error {
+		if len(tag) != 3 {
+			return fmt.Errorf("bad MARC field tag %q", tag)
+		}
+		if len(value) > 9999 {
+			return fmt.Errorf("MARC field %s too long: %d", tag, len(value))
+		}
+		fmt.Fprintf(&directory, "%s%04d%05d", tag, len(value), data.Len())
+		data.Write(value)
+		return nil
+	}
+
+	for _, field := range r.ControlFields {
+		value := append([]byte(field.Value), marcFieldTerminator)
+		if err := addField(field.Tag, value); err != nil {
+			return nil, err
+		}
+	}
+	for _, field := range r.DataFields {
+		var value bytes.Buffer
+		value.WriteString(indicator(field.Ind1))
+		value.WriteString(indicator(field.Ind2))
+		for _, sub := range field.Subfields {
+			value.WriteByte(marcSubfieldDelimiter)
+			value.WriteString(sub.Code)
+			value.WriteString(sub.Value)
+		}
+		value.WriteByte(marcFieldTerminator)
+		if err := addField(field.Tag, value.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	directory.WriteByte(marcFieldTerminator)
+	data.WriteByte(marcRecordTerminator)
+
+	var baseAddress = 24 + directory.Len()
+	var length = baseAddress + data.Len()
+	if length > 99999 {
+		return nil, fmt.Errorf("MARC record too long: %d", length)
+	}
+
+	var leader = []byte(r.Leader)
+	if len(leader) != 24 {
+		return nil, fmt.Errorf("bad MARC leader %q", r.Leader)
+	}
+	copy(leader[0:], fmt.Sprintf("%05d", length))
+	copy(leader[12:], fmt.Sprintf("%05d", baseAddress))
+
+	var record = make([]byte, 0, length)
+	record = append(record, leader...)
+	record = append(record, directory.Bytes()...)
+	record = append(record, data.Bytes()...)
+	return record, nil
+}
+
+// indicator return a single indicator character.
+func indicator(ind string) string {
+	if len(ind) != 1 {
+		return " "
+	}
+	return ind
+}
+
+// WriteISO2709 write records to w in MARC 21 exchange format.
+func WriteISO2709(w io.Writer, records ...*MARCRecord) error {
+	for _, record := range records {
+		data, err := record.ISO2709()
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteMARCXML write records to w as MARCXML collection.
+func WriteMARCXML(w io.Writer, records ...*MARCRecord) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(MARCCollection{Records: records}); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
@@ -0,0 +1,375 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ReadISO2709 return all records read from MARC 21 exchange format
+// (ISO 2709) stream.
+func ReadISO2709(r io.Reader) ([]*MARCRecord, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*MARCRecord
+	for {
+		// records may be separated by line breaks
+		if data = bytes.TrimLeft(data, " \t\r\n"); len(data) == 0 {
+			break
+		}
+		if len(data) < 24 {
+			return nil, fmt.Errorf("bad MARC record: too short")
+		}
+		length, ok := marcNumber(data[:5])
+		if !ok || length < 24 || length > len(data) {
+			return nil, fmt.Errorf("bad MARC record length %q", data[:5])
+		}
+		record, err := parseISO2709Record(data[:length])
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+		data = data[length:]
+	}
+	return records, nil
+}
+
+// parseISO2709Record return parsed single ISO 2709 record.
+func parseISO2709Record(data []byte) (*MARCRecord, error) {
+	var record = &MARCRecord{Leader: string(data[:24])}
+	baseAddress, ok := marcNumber(data[12:17])
+	if !ok || baseAddress < 25 || baseAddress > len(data) {
+		return nil, fmt.Errorf("bad MARC base address %q", data[12:17])
+	}
+
+	var directory = data[24 : baseAddress-1] // without field terminator
+	if len(directory)%12 != 0 {
+		return nil, fmt.Errorf("bad MARC directory length: %d", len(directory))
+	}
+	for ; len(directory) > 0; directory = directory[12:] {
+		tag := string(directory[:3])
+		length, ok1 := marcNumber(directory[3:7])
+		start, ok2 := marcNumber(directory[7:12])
+		if !ok1 || !ok2 ||
+			baseAddress+start+length > len(data) || length < 1 {
+			return nil, fmt.Errorf("bad MARC directory entry %q", directory[:12])
+		}
+		// without field terminator
+		value := data[baseAddress+start : baseAddress+start+length-1]
+
+		if strings.HasPrefix(tag, "00") {
+			record.ControlFields = append(record.ControlFields,
+				MARCControlField{Tag: tag, Value: string(value)})
+			continue
+		}
+
+		var field = MARCDataField{Tag: tag, Ind1: " ", Ind2: " "}
+		if len(value) >= 2 {
+			field.Ind1, field.Ind2 = string(value[0]), string(value[1])
+			value = value[2:]
+		}
+		for _, sub := range bytes.Split(value, []byte{marcSubfieldDelimiter}) {
+			if len(sub) == 0 {
+				continue
+			}
+			field.Subfields = append(field.Subfields,
+				MARCSubfield{Code: string(sub[0]), Value: string(sub[1:])})
+		}
+		record.DataFields = append(record.DataFields, field)
+	}
+	return record, nil
+}
+
+// marcNumber return the value of unsigned decimal number field. Signs and
+// spaces are not allowed.
+func marcNumber(data []byte) (int, bool) {
+	var n int
+	for _, c := range data {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, len(data) > 0
+}
+
+// ParseMARCXML return all records from MARCXML document. The document may
+// contain a collection or a single record, with or without namespace.
+func ParseMARCXML(data []byte) ([]*MARCRecord, error) {
+	var records []*MARCRecord
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "record" {
+			continue
+		}
+		start.Name.Space = "http://www.loc.gov/MARC21/slim"
+		var record = new(MARCRecord)
+		if err := dec.DecodeElement(record, &start); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// ParseMARC return publications from MARCXML or ISO 2709 data.
+func ParseMARC(data []byte) ([]*Publication, error) {
+	var (
+		records []*MARCRecord
+		err     error
+	)
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		records, err = ParseMARCXML(data)
+	} else {
+		records, err = ReadISO2709(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var pubs = make([]*Publication, len(records))
+	for i, record := range records {
+		pubs[i] = record.Publication()
+	}
+	return pubs, nil
+}
+
+var reYear = regexp.MustCompile(`\d{4}`)
+
+// trimISBD remove trailing ISBD punctuation.
+func trimISBD(s string) string {
+	return strings.TrimSpace(strings.TrimRight(strings.TrimSpace(s), ":;/,.="))
+}
+
+// Publication return publication metadata from MARC 21 record. Fields that
+// are not mapped are kept in Properties with "marc:" prefix.
+func (r *MARCRecord) Publication() *Publication {
+	var pub = new(Publication)
+	retain := func(tag, value string) {
+		if pub.Properties == nil {
+			pub.Properties = make(map[string]interface{})
+		}
+		key := "marc:" + tag
+		list, _ := pub.Properties[key].([]string)
+		pub.Properties[key] = append(list, value)
+	}
+
+	for _, field := range r.ControlFields {
+		if field.Tag == "008" && len(field.Value) >= 38 {
+			pub.Language = isoLanguage(strings.TrimSpace(field.Value[35:38]))
+			if strings.ContainsRune("sept", rune(field.Value[6])) &&
+				reYear.MatchString(field.Value[7:11]) {
+				pub.Date = Date(field.Value[7:11])
+			}
+			continue
+		}
+		retain(field.Tag, field.Value)
+	}
+
+	var (
+		date        Date
+		description []string
+	)
+	for _, field := range r.DataFields {
+		switch field.Tag {
+		case "020":
+			isbn := strings.Fields(field.Subfield("a"))
+			if len(isbn) == 0 {
+				continue
+			}
+			var scheme = "ISBN-13"
+			if digits := strings.ReplaceAll(isbn[0], "-", ""); len(digits) == 10 {
+				scheme = "ISBN-10"
+			}
+			pub.Identifier = append(pub.Identifier,
+				Identifier{Scheme: scheme, Text: isbn[0]})
+
+		case "024":
+			text := field.Subfield("a")
+			if text == "" {
+				continue
+			}
+			var id = Identifier{Text: text}
+			switch field.Ind1 {
+			case "1":
+				id.Scheme = "UPC"
+			case "2":
+				id.Scheme = "ISMN-13"
+			case "3":
+				id.Scheme = "GTIN-13"
+			case "7":
+				switch strings.ToLower(field.Subfield("2")) {
+				case "doi":
+					id.Scheme = "DOI"
+				case "uuid":
+					id.Scheme, id.Text = "UUID", "urn:uuid:"+text
+				case "urn":
+					id.Scheme = "URN"
+				}
+			}
+			pub.Identifier = append(pub.Identifier, id)
+
+		case "100", "700":
+			author := marcAuthor(field)
+			if author.Text == "" {
+				continue
+			}
+			if field.Tag == "100" || author.isCreator() {
+				pub.Creator = append(pub.Creator, author)
+			} else {
+				pub.Contributor = append(pub.Contributor, author)
+			}
+
+		case "245":
+			if main := trimISBD(field.Subfield("a")); main != "" {
+				pub.Title = append(Titles{{Type: "main", Text: main}}, pub.Title...)
+			}
+			if subtitle := trimISBD(field.Subfield("b")); subtitle != "" {
+				pub.Title = append(pub.Title, Title{Type: "subtitle", Text: subtitle})
+			}
+
+		case "246":
+			if text := trimISBD(field.Subfield("a")); text != "" {
+				pub.Title = append(pub.Title, Title{Type: "extended", Text: text})
+			}
+
+		case "250":
+			if text := trimISBD(field.Subfield("a")); text != "" {
+				pub.Title = append(pub.Title, Title{Type: "edition", Text: text})
+			}
+
+		case "260", "264":
+			if field.Tag == "264" && field.Ind2 != "1" {
+				retain(field.Tag, field.mnemonic())
+				continue // not a publication statement
+			}
+			if publisher := trimISBD(field.Subfield("b")); publisher != "" &&
+				pub.Publisher == "" {
+				pub.Publisher = publisher
+			}
+			if year := reYear.FindString(field.Subfield("c")); year != "" &&
+				date == "" {
+				date = Date(year)
+			}
+
+		case "490":
+			if collection := trimISBD(field.Subfield("a")); collection != "" {
+				pub.BelongsToCollection = collection
+				pub.GroupPosition = trimISBD(field.Subfield("v"))
+			}
+
+		case "520":
+			if text := strings.TrimSpace(field.Subfield("a")); text != "" {
+				description = append(description, text)
+			}
+
+		case "540":
+			if pub.Rights == "" {
+				pub.Rights = strings.TrimSpace(field.Subfield("a"))
+			}
+
+		case "650":
+			var parts []string
+			for _, sub := range field.Subfields {
+				switch sub.Code {
+				case "a", "x", "y", "z", "v":
+					parts = append(parts, trimISBD(sub.Value))
+				}
+			}
+			if len(parts) > 0 {
+				pub.Subject = append(pub.Subject, strings.Join(parts, " -- "))
+			}
+
+		default:
+			retain(field.Tag, field.mnemonic())
+		}
+	}
+
+	if date != "" {
+		pub.Date = date
+	}
+	pub.Description = strings.Join(description, "\n\n")
+	return pub
+}
+
+// marcAuthor return author from MARC 100/700 field.
+func marcAuthor(field MARCDataField) Author {
+	var name = trimISBD(field.Subfield("a"))
+	var author = Author{Text: name}
+	if field.Ind1 == "1" {
+		if i := strings.Index(name, ","); i > 0 {
+			author.FileAs = name
+			author.Text = strings.TrimSpace(name[i+1:]) + " " + name[:i]
+		}
+	}
+
+	var codes = make(map[string]bool)
+	for _, sub := range field.Subfields {
+		if sub.Code != "4" && sub.Code != "e" {
+			continue
+		}
+		code, ok := LookupRelator(trimISBD(sub.Value), "")
+		if !ok || codes[code] {
+			continue
+		}
+		codes[code] = true
+		author.Role = append(author.Role, code)
+	}
+	return author
+}
+
+// isCreator return true if author has a primary creator role.
+func (author Author) isCreator() bool {
+	for _, code := range author.MARCs() {
+		if code == "aut" || code == "cre" {
+			return true
+		}
+	}
+	return false
+}
+
+// mnemonic return field in MARC mnemonic form with `\` for blank
+// indicators: `10$aTitle$bsubtitle`.
+func (f MARCDataField) mnemonic() string {
+	var b strings.Builder
+	for _, ind := range []string{f.Ind1, f.Ind2} {
+		if ind = indicator(ind); ind == " " {
+			ind = `\`
+		}
+		b.WriteString(ind)
+	}
+	for _, sub := range f.Subfields {
+		b.WriteByte('$')
+		b.WriteString(sub.Code)
+		b.WriteString(sub.Value)
+	}
+	return b.String()
+}
+
+// isoLanguage return ISO 639-1 language code for MARC language code if
+// defined.
+func isoLanguage(code string) string {
+	for iso, marc := range MARCLanguages {
+		if marc == code {
+			return iso
+		}
+	}
+	if code == "und" || strings.Trim(code, "|") == "" {
+		return ""
+	}
+	return code
+}
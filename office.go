@@ -0,0 +1,428 @@
+package metadata
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Office document metadata parts.
+const (
+	OOXMLCorePart = "docProps/core.xml"
+	ODFMetaPart   = "meta.xml"
+)
+
+// ooxmlCore is an OOXML core properties part for writing.
+type ooxmlCore struct {
+	XMLName     xml.Name   `xml:"cp:coreProperties"`
+	CP          string     `xml:"xmlns:cp,attr"`
+	DC          string     `xml:"xmlns:dc,attr"`
+	DCTerms     string     `xml:"xmlns:dcterms,attr"`
+	XSI         string     `xml:"xmlns:xsi,attr"`
+	Title       string     `xml:"dc:title,omitempty"`
+	Creator     string     `xml:"dc:creator,omitempty"`
+	Keywords    string     `xml:"cp:keywords,omitempty"`
+	Description string     `xml:"dc:description,omitempty"`
+	Identifier  string     `xml:"dc:identifier,omitempty"`
+	Language    string     `xml:"dc:language,omitempty"`
+	Category    string     `xml:"cp:category,omitempty"`
+	Version     string     `xml:"cp:version,omitempty"`
+	Created     *ooxmlDate `xml:"dcterms:created,omitempty"`
+	Modified    *ooxmlDate `xml:"dcterms:modified,omitempty"`
+}
+
+type ooxmlDate struct {
+	Type  string `xml:"xsi:type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// officeProperties are the common document properties for reading. Only
+// local names are used, so it fits both OOXML and ODF.
+type officeProperties struct {
+	Title         string   `xml:"title"`
+	Subject       string   `xml:"subject"`
+	Creator       string   `xml:"creator"`
+	InitialAuthor string   `xml:"initial-creator"`
+	Keywords      string   `xml:"keywords"`
+	Keyword       []string `xml:"keyword"`
+	Description   string   `xml:"description"`
+	Identifier    string   `xml:"identifier"`
+	Language      string   `xml:"language"`
+	Category      string   `xml:"category"`
+	Version       string   `xml:"version"`
+	Created       string   `xml:"created"`
+	CreationDate  string   `xml:"creation-date"`
+	Modified      string   `xml:"modified"`
+	Date          string   `xml:"date"`
+	UserDefined   []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"user-defined"`
+}
+
+// OOXMLCore return publication metadata as OOXML (DOCX) core properties
+// part docProps/core.xml.
+func (p Publication) OOXMLCore() ([]byte, error) {
+	var core = ooxmlCore{
+		CP:          "http://schemas.openxmlformats.org/package/2006/metadata/core-properties",
+		DC:          "http://purl.org/dc/elements/1.1/",
+		DCTerms:     "http://purl.org/dc/terms/",
+		XSI:         "http://www.w3.org/2001/XMLSchema-instance",
+		Title:       p.Title.Main(),
+		Creator:     strings.Join(p.creatorNames(), "; "),
+		Keywords:    strings.Join(p.Subject, ", "),
+		Description: p.DescriptionText(),
+		Language:    p.Language,
+		Category:    p.Type,
+		Version:     string(p.Version),
+	}
+	if id, ok := p.Identifier.primary(); ok {
+		core.Identifier = id.Text
+	}
+	if p.Date != "" {
+		core.Created = &ooxmlDate{Type: "dcterms:W3CDTF", Value: string(p.Date)}
+	}
+	if p.Modified != "" {
+		core.Modified = &ooxmlDate{Type: "dcterms:W3CDTF", Value: string(p.Modified)}
+	}
+	return officeXML(core)
+}
+
+// odfMeta is an ODF meta.xml document for writing.
+type odfMeta struct {
+	XMLName xml.Name `xml:"office:document-meta"`
+	Office  string   `xml:"xmlns:office,attr"`
+	Meta    string   `xml:"xmlns:meta,attr"`
+	DC      string   `xml:"xmlns:dc,attr"`
+	Version string   `xml:"office:version,attr"`
+	Body    struct {
+		Title        string           `xml:"dc:title,omitempty"`
+		Description  string           `xml:"dc:description,omitempty"`
+		Keyword      []string         `xml:"meta:keyword,omitempty"`
+		Creator      string           `xml:"meta:initial-creator,omitempty"`
+		CreationDate string           `xml:"meta:creation-date,omitempty"`
+		Date         string           `xml:"dc:date,omitempty"`
+		Language     string           `xml:"dc:language,omitempty"`
+		UserDefined  []odfUserDefined `xml:"meta:user-defined,omitempty"`
+	} `xml:"office:meta"`
+}
+
+type odfUserDefined struct {
+	Name  string `xml:"meta:name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// ODFMeta return publication metadata as ODF (ODT) meta.xml part. Simple
+// Properties are written as user defined fields.
+func (p Publication) ODFMeta() ([]byte, error) {
+	var meta = odfMeta{
+		Office:  "urn:oasis:names:tc:opendocument:xmlns:office:1.0",
+		Meta:    "urn:oasis:names:tc:opendocument:xmlns:meta:1.0",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Version: "1.2",
+	}
+	var body = &meta.Body
+	body.Title = p.Title.Main()
+	body.Description = p.DescriptionText()
+	body.Keyword = p.Subject
+	body.Creator = strings.Join(p.creatorNames(), "; ")
+	body.CreationDate = string(p.Date)
+	body.Date = string(p.Modified)
+	body.Language = p.Language
+
+	var names = make([]string, 0, len(p.Properties))
+	for name := range p.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch value := p.Properties[name].(type) {
+		case string, int, int64, float64, bool:
+			body.UserDefined = append(body.UserDefined,
+				odfUserDefined{Name: name, Value: fmt.Sprint(value)})
+		}
+	}
+	return officeXML(meta)
+}
+
+// officeXML return encoded XML document with header.
+func officeXML(v interface{}) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", " ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// creatorNames return the list of creators names in display order.
+func (p Publication) creatorNames() []string {
+	var names = make([]string, 0, len(p.Creator))
+	for _, author := range p.Creator.Sorted() {
+		names = append(names, author.Text)
+	}
+	return names
+}
+
+// ParseOfficeMeta return publication metadata from OOXML core properties
+// or ODF meta.xml part.
+func ParseOfficeMeta(data []byte) (*Publication, error) {
+	var root struct {
+		officeProperties
+		Meta *officeProperties `xml:"meta"` // ODF office:meta
+	}
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	var props = root.officeProperties
+	if root.Meta != nil {
+		props = *root.Meta
+	}
+
+	var pub = &Publication{
+		Description: strings.TrimSpace(props.Description),
+		Language:    strings.TrimSpace(props.Language),
+		Type:        strings.TrimSpace(props.Category),
+	}
+	if title := strings.TrimSpace(props.Title); title != "" {
+		pub.Title = Titles{{Type: "main", Text: title}}
+	}
+
+	var creators = props.InitialAuthor
+	if creators == "" {
+		creators = props.Creator
+	}
+	for _, name := range strings.Split(creators, ";") {
+		if name = strings.TrimSpace(name); name != "" {
+			pub.Creator = append(pub.Creator, Author{Text: name})
+		}
+	}
+
+	var keywords = props.Keyword
+	keywords = append(keywords, strings.FieldsFunc(props.Keywords, func(r rune) bool {
+		return r == ',' || r == ';'
+	})...)
+	if props.Subject != "" {
+		keywords = append(keywords, props.Subject)
+	}
+	for _, keyword := range keywords {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			pub.Subject = append(pub.Subject, keyword)
+		}
+	}
+
+	if text := strings.TrimSpace(props.Identifier); text != "" {
+		var id = Identifier{Text: text}
+		id.detectScheme()
+		pub.Identifier = Identifiers{id}
+	}
+	if v := strings.TrimSpace(props.Version); v != "" && checkVersionFormat(v) == nil {
+		pub.Version = Version(v)
+	}
+
+	for _, date := range []string{props.Created, props.CreationDate} {
+		if date := xmpDate(date); date != "" {
+			pub.Date = date
+		}
+	}
+	for _, date := range []string{props.Modified, props.Date} {
+		if date := xmpDate(date); date != "" {
+			pub.Modified = date
+		}
+	}
+
+	for _, field := range props.UserDefined {
+		if field.Name != "" {
+			pub.setProperty(field.Name, strings.TrimSpace(field.Value))
+		}
+	}
+
+	return pub, nil
+}
+
+// LoadOffice return publication metadata from DOCX or ODT file.
+func LoadOffice(filename string) (*Publication, error) {
+	z, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+
+	for _, file := range z.File {
+		if file.Name != OOXMLCorePart && file.Name != ODFMetaPart {
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+		return ParseOfficeMeta(data)
+	}
+	return nil, fmt.Errorf("%s: document properties not found", filename)
+}
+
+// StampOffice copy DOCX or ODT file src to dst with publication metadata
+// merged into its document properties part. Properties not defined by
+// publication, like statistics or revision, are kept. The properties part
+// must exist in the source document.
+func (p Publication) StampOffice(src, dst string) (err error) {
+	z, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer z.Close()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	var stamped bool
+	for _, file := range z.File {
+		var header = file.FileHeader // copy
+		fw, err := w.CreateHeader(&header)
+		if err != nil {
+			return err
+		}
+
+		var r io.ReadCloser
+		if r, err = file.Open(); err != nil {
+			return err
+		}
+		switch file.Name {
+		case OOXMLCorePart, ODFMetaPart:
+			var data, part []byte
+			data, err = io.ReadAll(r)
+			if err == nil {
+				if file.Name == OOXMLCorePart {
+					part, err = p.OOXMLCore()
+				} else {
+					part, err = p.ODFMeta()
+				}
+			}
+			if err == nil {
+				_, err = fw.Write(mergeOfficeXML(data, part))
+			}
+			stamped = true
+		default:
+			_, err = io.Copy(fw, r)
+		}
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if !stamped {
+		return fmt.Errorf("%s: document properties not found", src)
+	}
+	return os.WriteFile(dst, buf.Bytes(), 0644)
+}
+
+// officeElement is a position of the properties element in XML data.
+type officeElement struct {
+	key        string // local name and user defined field name
+	start, end int64
+}
+
+// officeElements return the positions of document properties elements,
+// the end position of properties content and the declared name spaces.
+// The properties are the children of OOXML root or ODF office:meta
+// element.
+func officeElements(data []byte) (elements []officeElement, end int64, ns map[string]string, err error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	ns = make(map[string]string)
+	var depth, container = 0, -1
+	for {
+		var offset = dec.InputOffset()
+		token, err := dec.RawToken()
+		if err == io.EOF {
+			return elements, end, ns, nil
+		}
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case container < 0:
+				for _, attr := range token.Attr {
+					if attr.Name.Space == "xmlns" {
+						ns[attr.Name.Local] = attr.Value
+					}
+				}
+				if (depth == 1 && token.Name.Local != "document-meta") ||
+					(depth == 2 && token.Name.Local == "meta") {
+					container = depth
+				}
+			case depth == container+1:
+				var key = token.Name.Local
+				if key == "user-defined" {
+					for _, attr := range token.Attr {
+						if attr.Name.Local == "name" {
+							key += "/" + attr.Value
+						}
+					}
+				}
+				elements = append(elements, officeElement{key: key, start: offset})
+			}
+		case xml.EndElement:
+			switch depth {
+			case container + 1:
+				elements[len(elements)-1].end = dec.InputOffset()
+			case container:
+				end = offset
+			}
+			depth--
+		}
+	}
+}
+
+// mergeOfficeXML return the document properties part data with elements
+// replaced and added from the part generated from publication. The
+// generated part is returned if the data can't be merged.
+func mergeOfficeXML(data, part []byte) []byte {
+	elements, end, ns, err := officeElements(data)
+	if err != nil || end == 0 {
+		return part
+	}
+	replace, _, partNS, err := officeElements(part)
+	if err != nil {
+		return part
+	}
+	for prefix, uri := range partNS {
+		if ns[prefix] != uri {
+			return part // different name space prefixes
+		}
+	}
+
+	var keys = make(map[string]bool, len(replace))
+	for _, el := range replace {
+		keys[el.key] = true
+	}
+	var buf bytes.Buffer
+	var offset int64
+	for _, el := range elements {
+		if keys[el.key] {
+			buf.Write(bytes.TrimRight(data[offset:el.start], " \t\r\n"))
+			offset = el.end
+		}
+	}
+	buf.Write(bytes.TrimRight(data[offset:end], " \t\r\n"))
+	for _, el := range replace {
+		buf.WriteString("\n ")
+		buf.Write(part[el.start:el.end])
+	}
+	buf.WriteString("\n")
+	buf.Write(data[end:])
+	return buf.Bytes()
+}
@@ -0,0 +1,278 @@
+package metadata
+
+import (
+	"encoding/xml"
+	"strings"
+	"time"
+)
+
+// OPDS link relations.
+const (
+	OPDSAcquisition = "http://opds-spec.org/acquisition"
+	OPDSImage       = "http://opds-spec.org/image"
+	OPDSThumbnail   = "http://opds-spec.org/image/thumbnail"
+)
+
+// OPDSSummaryLength is the maximum length of OPDS entry summary text.
+var OPDSSummaryLength = 300
+
+// OPDSLink is a link of OPDS catalog entry or feed.
+type OPDSLink struct {
+	Rel   string `xml:"rel,attr,omitempty" json:"rel,omitempty"`
+	Href  string `xml:"href,attr" json:"href"`
+	Type  string `xml:"type,attr,omitempty" json:"type,omitempty"`
+	Title string `xml:"title,attr,omitempty" json:"title,omitempty"`
+}
+
+// isImage return true for cover and thumbnail links.
+func (link OPDSLink) isImage() bool {
+	return strings.HasPrefix(link.Rel, OPDSImage)
+}
+
+// OPDSLinkProvider return links for publication. It is supplied by caller
+// to define acquisition and cover links of catalog entries.
+type OPDSLinkProvider func(pub *Publication) []OPDSLink
+
+// OPDSEntry is an OPDS 1.2 Atom catalog entry.
+//
+// https://specs.opds.io/opds-1.2
+type OPDSEntry struct {
+	XMLName    xml.Name       `xml:"entry"`
+	NS         string         `xml:"xmlns,attr,omitempty"`      // for standalone entry only
+	DC         string         `xml:"xmlns:dc,attr,omitempty"`   // for standalone entry only
+	OPDS       string         `xml:"xmlns:opds,attr,omitempty"` // for standalone entry only
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Updated    string         `xml:"updated"`
+	Authors    []OPDSAuthor   `xml:"author,omitempty"`
+	Language   string         `xml:"dc:language,omitempty"`
+	Issued     string         `xml:"dc:issued,omitempty"`
+	Identifier []string       `xml:"dc:identifier,omitempty"`
+	Publisher  string         `xml:"dc:publisher,omitempty"`
+	IsPartOf   string         `xml:"dc:isPartOf,omitempty"`
+	Rights     string         `xml:"rights,omitempty"`
+	Summary    *OPDSText      `xml:"summary,omitempty"`
+	Content    *OPDSText      `xml:"content,omitempty"`
+	Categories []OPDSCategory `xml:"category,omitempty"`
+	Links      []OPDSLink     `xml:"link"`
+}
+
+// OPDSAuthor is an Atom person construct.
+// The sort form of the name is defined as opds:sortAs extension element.
+type OPDSAuthor struct {
+	Name   string `xml:"name"`
+	SortAs string `xml:"opds:sortAs,omitempty"`
+	URI    string `xml:"uri,omitempty"`
+}
+
+// OPDSText is an Atom text construct.
+type OPDSText struct {
+	Type  string `xml:"type,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+// OPDSCategory is an Atom category.
+type OPDSCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr,omitempty"`
+}
+
+// OPDSFeed describe OPDS catalog feed properties and the link providers
+// for entries.
+type OPDSFeed struct {
+	ID          string
+	Title       string
+	Updated     time.Time
+	Links       []OPDSLink       // feed links: self, start, etc.
+	Acquisition OPDSLinkProvider // acquisition links for publication
+	Cover       OPDSLinkProvider // cover and thumbnail links for publication
+}
+
+// AtomFeed is an OPDS 1.2 Atom feed.
+type AtomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	DC      string      `xml:"xmlns:dc,attr"`
+	OPDS    string      `xml:"xmlns:opds,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []OPDSLink  `xml:"link"`
+	Entries []OPDSEntry `xml:"entry"`
+}
+
+// OPDS2Publication is an OPDS 2.0 publication object.
+//
+// https://drafts.opds.io/opds-2.0
+type OPDS2Publication struct {
+	Metadata ReadiumMetadata `json:"metadata"`
+	Links    []OPDSLink      `json:"links"`
+	Images   []OPDSLink      `json:"images,omitempty"`
+}
+
+// OPDS2Feed is an OPDS 2.0 feed.
+type OPDS2Feed struct {
+	Metadata struct {
+		Title    string `json:"title"`
+		Modified string `json:"modified,omitempty"`
+	} `json:"metadata"`
+	Links        []OPDSLink         `json:"links"`
+	Publications []OPDS2Publication `json:"publications"`
+}
+
+// URN return identifier as URN: urn:isbn, urn:uuid or urn:doi.
+func (id Identifier) URN() string {
+	var text = id.Text
+	if strings.HasPrefix(text, "urn:") {
+		return text
+	}
+	switch scheme := strings.ToUpper(id.Scheme); {
+	case strings.HasPrefix(scheme, "ISBN"):
+		if digits, _ := standardNumber(text, isbnPrefixes...); digits != "" {
+			return "urn:isbn:" + digits
+		}
+	case scheme == "UUID":
+		return "urn:uuid:" + strings.Trim(text, "{}")
+	case scheme == "DOI":
+		return "urn:doi:" + strings.TrimPrefix(text, "doi:")
+	}
+	return text
+}
+
+// updated return the last modification time of publication in RFC 3339
+// format or fallback time if not defined.
+func (p Publication) updated(fallback time.Time) string {
+	for _, date := range []Date{p.Modified, p.Date} {
+		for _, layout := range []string{time.RFC3339, "2006-01-02", "2006-01", "2006"} {
+			if t, err := time.Parse(layout, string(date)); err == nil {
+				return t.UTC().Format(time.RFC3339)
+			}
+		}
+	}
+	if fallback.IsZero() {
+		fallback = time.Now()
+	}
+	return fallback.UTC().Format(time.RFC3339)
+}
+
+// OPDSEntry return publication as OPDS 1.2 Atom catalog entry with links.
+func (p Publication) OPDSEntry(links ...OPDSLink) OPDSEntry {
+	entry := p.opdsEntry(time.Time{}, links)
+	entry.NS = "http://www.w3.org/2005/Atom"
+	entry.DC = "http://purl.org/dc/terms/"
+	entry.OPDS = "http://opds-spec.org/2010/catalog"
+	return entry
+}
+
+func (p Publication) opdsEntry(updated time.Time, links []OPDSLink) OPDSEntry {
+	var entry = OPDSEntry{
+		Title:     p.Title.Main(),
+		Updated:   p.updated(updated),
+		Language:  p.Language,
+		Issued:    string(p.Date),
+		Publisher: p.Publisher,
+		IsPartOf:  p.BelongsToCollection,
+		Rights:    p.RightsText(),
+		Links:     links,
+	}
+
+	for _, id := range p.Identifier {
+		entry.Identifier = append(entry.Identifier, id.URN())
+	}
+	if i := p.Identifier.Primary(); i >= 0 {
+		entry.ID = entry.Identifier[i]
+	} else if uuid, err := p.UUID(""); err == nil {
+		// atom:id is required
+		entry.ID = "urn:uuid:" + uuid
+	}
+
+	for _, author := range p.Creator.Sorted() {
+		entry.Authors = append(entry.Authors,
+			OPDSAuthor{Name: author.Text, SortAs: author.FileAs})
+	}
+
+	if summary := p.Teaser(OPDSSummaryLength); summary != "" {
+		entry.Summary = &OPDSText{Type: "text", Value: summary}
+	}
+	if content := p.DescriptionHTML(); content != "" {
+		entry.Content = &OPDSText{Type: "html", Value: content}
+	}
+
+	for _, subject := range p.Subject {
+		entry.Categories = append(entry.Categories,
+			OPDSCategory{Term: subject, Label: subject})
+	}
+
+	return entry
+}
+
+// OPDSPublication return publication as OPDS 2.0 publication object with
+// links. Image links are placed to images list.
+func (p Publication) OPDSPublication(links ...OPDSLink) OPDS2Publication {
+	var pub = OPDS2Publication{
+		Metadata: p.Readium(),
+		Links:    make([]OPDSLink, 0, len(links)),
+	}
+	if id, ok := p.Identifier.primary(); ok {
+		pub.Metadata.Identifier = id.URN()
+	}
+	for _, link := range links {
+		if link.isImage() {
+			pub.Images = append(pub.Images, link)
+		} else {
+			pub.Links = append(pub.Links, link)
+		}
+	}
+	return pub
+}
+
+// links return all links for publication from providers.
+func (f OPDSFeed) links(pub *Publication) []OPDSLink {
+	var links []OPDSLink
+	for _, provider := range []OPDSLinkProvider{f.Acquisition, f.Cover} {
+		if provider != nil {
+			links = append(links, provider(pub)...)
+		}
+	}
+	return links
+}
+
+// Atom return OPDS 1.2 acquisition feed with publications.
+func (f OPDSFeed) Atom(pubs []*Publication) *AtomFeed {
+	var updated = f.Updated
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	var feed = &AtomFeed{
+		DC:      "http://purl.org/dc/terms/",
+		OPDS:    "http://opds-spec.org/2010/catalog",
+		ID:      f.ID,
+		Title:   f.Title,
+		Updated: updated.UTC().Format(time.RFC3339),
+		Links:   f.Links,
+		Entries: make([]OPDSEntry, 0, len(pubs)),
+	}
+	for _, pub := range pubs {
+		feed.Entries = append(feed.Entries, pub.opdsEntry(updated, f.links(pub)))
+	}
+	return feed
+}
+
+// JSON return OPDS 2.0 feed with publications.
+func (f OPDSFeed) JSON(pubs []*Publication) *OPDS2Feed {
+	var feed = &OPDS2Feed{
+		Links:        f.Links,
+		Publications: make([]OPDS2Publication, 0, len(pubs)),
+	}
+	feed.Metadata.Title = f.Title
+	if !f.Updated.IsZero() {
+		feed.Metadata.Modified = f.Updated.UTC().Format(time.RFC3339)
+	}
+	if feed.Links == nil {
+		feed.Links = []OPDSLink{}
+	}
+	for _, pub := range pubs {
+		feed.Publications = append(feed.Publications,
+			pub.OPDSPublication(f.links(pub)...))
+	}
+	return feed
+}
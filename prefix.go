@@ -0,0 +1,92 @@
+package metadata
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	epub "github.com/mdigger/epub3"
+)
+
+// vocabularies are the known EPUB metadata vocabularies IRI by prefix.
+var vocabularies = map[string]string{
+	"ibooks":  "http://vocabulary.itunes.apple.com/rdf/ibooks/vocabulary-extensions-1.0/",
+	"calibre": "https://calibre-ebook.com",
+	"cc":      "http://creativecommons.org/ns#",
+}
+
+// reservedPrefixes are the EPUB 3 reserved prefixes which must not be
+// declared.
+var reservedPrefixes = map[string]bool{
+	"a11y": true, "dcterms": true, "marc": true, "media": true, "onix": true,
+	"rendition": true, "schema": true, "xsd": true, "msv": true, "prism": true,
+}
+
+// RegisterPrefix register EPUB metadata vocabulary prefix IRI, for example
+// for the extension properties. Reserved prefixes are ignored. It should be
+// called on initialization only.
+func RegisterPrefix(prefix, iri string) {
+	if !reservedPrefixes[prefix] {
+		vocabularies[prefix] = iri
+	}
+}
+
+// usedPrefixes return the sorted list of vocabulary prefixes used in
+// metadata properties, schemes and link relations.
+func usedPrefixes(meta epub.Metadata) []string {
+	var used = make(map[string]bool)
+	add := func(names ...string) {
+		for _, name := range names {
+			if i := strings.IndexByte(name, ':'); i > 0 {
+				used[name[:i]] = true
+			}
+		}
+	}
+	for _, m := range meta.Meta {
+		add(m.Property, m.Scheme)
+	}
+	for _, l := range meta.Link {
+		add(strings.Fields(l.Rel)...)
+	}
+
+	var prefixes = make([]string, 0, len(used))
+	for prefix := range used {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+// EPUBPrefix return the package prefix attribute value declaring not
+// reserved vocabulary prefixes used in metadata. Return error with the list
+// of prefixes which are not registered, the declaration for other ones is
+// returned anyway.
+func EPUBPrefix(meta epub.Metadata) (string, error) {
+	var declarations, unknown []string
+	for _, prefix := range usedPrefixes(meta) {
+		if reservedPrefixes[prefix] {
+			continue
+		}
+		if iri, ok := vocabularies[prefix]; ok {
+			declarations = append(declarations, prefix+": "+iri)
+		} else {
+			unknown = append(unknown, prefix)
+		}
+	}
+	var prefix = strings.Join(declarations, " ")
+	if len(unknown) > 0 {
+		return prefix, fmt.Errorf("unknown vocabulary prefix: %s", strings.Join(unknown, ", "))
+	}
+	return prefix, nil
+}
+
+// validatePrefixes check that prefixes of extension properties are
+// reserved or registered.
+func validatePrefixes(p Publication) (warnings []Warning) {
+	var meta epub.Metadata
+	meta.Meta, meta.Link = p.extensionsEPUB()
+	if _, err := EPUBPrefix(meta); err != nil {
+		warnings = append(warnings, Warning{Field: "properties", Message: err.Error()})
+	}
+	return warnings
+}
@@ -3,7 +3,9 @@ package metadata
 import (
 	"fmt"
 	"os"
-	"strings"
+	"path/filepath"
+	"sort"
+	"strconv"
 
 	epub "github.com/mdigger/epub3"
 	"gopkg.in/yaml.v3"
@@ -15,10 +17,13 @@ type Publication struct {
 	Title               Titles      `yaml:"title"`
 	Language            string      `yaml:"lang,omitempty"` // or legacy: language
 	Date                Date        `yaml:"date,omitempty"`
+	Modified            Date        `yaml:"modified,omitempty"` // last modification date
+	Version             Version     `yaml:"version,omitempty"`  // document version
 	Creator             Authors     `yaml:"creator"`
 	Contributor         Authors     `yaml:"contributor,omitempty"`
 	Subject             Strings     `yaml:"subject,omitempty,flow"`
-	Description         string      `yaml:"description,omitempty"`
+	Description         string      `yaml:"description,omitempty"`       // Markdown or HTML
+	ShortDescription    string      `yaml:"short-description,omitempty"` // teaser text
 	Type                string      `yaml:"type,omitempty"`
 	Format              string      `yaml:"format,omitempty"`
 	Publisher           string      `yaml:"publisher,omitempty"`
@@ -26,20 +31,43 @@ type Publication struct {
 	Relation            string      `yaml:"relation,omitempty"`
 	Coverage            string      `yaml:"coverage,omitempty"`
 	Rights              string      `yaml:"rights,omitempty"`
+	CopyrightHolder     string      `yaml:"copyright-holder,omitempty"`
+	CopyrightYear       int         `yaml:"copyright-year,omitempty"`
+	License             string      `yaml:"license,omitempty"` // SPDX identifier or license URL
+	LicenseURL          string      `yaml:"license-url,omitempty"`
 	BelongsToCollection string      `yaml:"belongs-to-collection,omitempty"` // identifies the name of a collection to which the EPUB Publication belongs.
 	GroupPosition       string      `yaml:"group-position,omitempty"`        // indicates the numeric position in which the EPUB Publication belongs relative to other works belonging to the same belongs-to-collection field.
-	CoverImage          string      `yaml:"cover-image,omitempty"`
-	Stylesheets         []string    `yaml:"css,omitempty"` // or legacy: stylesheet
-	// PageDirection
-	IBooks *struct {
-		Version        Version `yaml:"version,omitempty"`
-		SpecifiedFonts bool    `yaml:"specified-fonts,omitempty"`
-	} `yaml:"ibooks,omitempty"`
+	CoverImage          *CoverImage `yaml:"cover-image,omitempty"`
+	Stylesheets         []string    `yaml:"css,omitempty"`            // or legacy: stylesheet
+	PageDirection       string      `yaml:"page-direction,omitempty"` // ltr, rtl or default
+	Layout              string      `yaml:"layout,omitempty"`         // reflowable or pre-paginated
+	Duration            Duration    `yaml:"duration,omitempty"`       // audiobook total duration
+	Abridged            bool        `yaml:"abridged,omitempty"`
+	ReadingOrder        []Track     `yaml:"reading-order,omitempty"` // audiobook tracks
+	IBooks              *Apple      `yaml:"ibooks,omitempty"`        // Apple Books options
+	Kobo                *Kobo       `yaml:"kobo,omitempty"`
+	Kindle              *Kindle     `yaml:"kindle,omitempty"`
+
 	Properties map[string]interface{} `yaml:",omitempty,inline"`
+	Extensions map[string]Extension   `yaml:"-"` // registered extension properties
+
+	dir string // metadata file directory for relative paths
+}
+
+// ParseOptions define the optional publication metadata processing.
+type ParseOptions struct {
+	AssignUUID    bool   // add urn:uuid: identifier if no one is defined
+	UUIDNamespace string // namespace for UUID; UUIDNamespace if empty
 }
 
 // Parse return parsed publication metadata.
 func Parse(data []byte) (*Publication, error) {
+	return ParseWithOptions(data, ParseOptions{})
+}
+
+// ParseWithOptions return parsed publication metadata processed with
+// options.
+func ParseWithOptions(data []byte, opts ParseOptions) (*Publication, error) {
 	pub := new(Publication)
 	if err := yaml.Unmarshal(data, pub); err != nil {
 		return nil, err
@@ -70,31 +98,165 @@ func Parse(data []byte) (*Publication, error) {
 		delete(pub.Properties, "stylesheet")
 	}
 
+	if err := pub.decodeExtensions(data); err != nil {
+		return nil, err
+	}
+
+	// derived identifier
+	if opts.AssignUUID && len(pub.Identifier) == 0 {
+		uuid, err := pub.UUID(opts.UUIDNamespace)
+		if err != nil {
+			return nil, err
+		}
+		pub.Identifier = Identifiers{{Scheme: "UUID", Text: "urn:uuid:" + uuid}}
+	}
+
 	return pub, nil
 }
 
-// Load return parsed publication metadata from file.
+type pubType Publication // alias
+
+// MarshalYAML implement yaml.Marshaler interface. The empty identifier is
+// commented with the UUID derived from publication metadata.
+func (p Publication) MarshalYAML() (interface{}, error) {
+	var node yaml.Node
+	if err := node.Encode((*pubType)(&p)); err != nil {
+		return nil, err
+	}
+
+	// extensions
+	var names = make([]string, 0, len(p.Extensions))
+	for name := range p.Extensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var key, value yaml.Node
+		key.SetString(name)
+		if err := value.Encode(p.Extensions[name]); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &key, &value)
+	}
+
+	if len(p.Identifier) > 0 {
+		return &node, nil
+	}
+	uuid, err := p.UUID("")
+	if err != nil {
+		return &node, nil // nothing to derive from
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "identifier" {
+			node.Content[i+1].LineComment = "urn:uuid:" + uuid
+			break
+		}
+	}
+	return &node, nil
+}
+
+// Load return parsed publication metadata from file. The cover image media
+// type and dimensions are read from the image file relative to the metadata
+// file if they are not defined.
 func Load(filename string) (*Publication, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
-	return Parse(data)
+	pub, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	pub.dir = filepath.Dir(filename)
+	if pub.CoverImage != nil {
+		_ = pub.CoverImage.Sniff(pub.dir) // reported by Validate
+	}
+	return pub, nil
 }
 
-// EPUB return converted to EPUB3 Metadata data.
-func (p Publication) EPUB() (meta epub.Metadata) {
-	meta.DC = "http://purl.org/dc/elements/1.1/" // add namespace
+// EPUBOptions define the EPUB metadata conversion parameters. The zero
+// value gives the default conversion used by EPUB.
+type EPUBOptions struct {
+	IDPrefix          string // elements ID prefix, "pub-" by default
+	IDFormat          string // ID position suffix format, "-%02d" by default
+	KeepDescription   bool   // use the sanitized XHTML description instead of plain text
+	SkipMainTitleType bool   // do not refine titles with the default type "main"
+	SkipFileAs        bool   // do not emit file-as refinements
+	// IdentifierType define the identifier-type refinements: "onix" (default)
+	// for ONIX codelist 5 values, "scheme" for identifier scheme names
+	// without vocabulary or "none".
+	IdentifierType string
+	// PostProcess is called with converted metadata before return.
+	PostProcess func(meta *epub.Metadata)
+}
 
-	// generate ID function
-	generateID := func(prefix string, position, total int) string {
-		prefix = fmt.Sprintf("pub-%s", prefix) // add prefix
-		if total <= 1 {
-			return prefix // return with prefix & id name
-		}
-		// add position number as suffix
-		return fmt.Sprintf("%s-%02d", prefix, position+1)
+// id return the element ID for metadata element at position.
+func (opts EPUBOptions) id(name string, position, total int) string {
+	var prefix, format = opts.IDPrefix, opts.IDFormat
+	if prefix == "" {
+		prefix = "pub-"
 	}
+	if format == "" {
+		format = "-%02d"
+	}
+	if total <= 1 {
+		return prefix + name // return with prefix & id name
+	}
+	// add position number as suffix
+	return prefix + name + fmt.Sprintf(format, position+1)
+}
+
+// UniqueIdentifier return the ID of primary dc:identifier element for the
+// package unique-identifier attribute. Return empty string if no identifier
+// is defined.
+func (p Publication) UniqueIdentifier() string {
+	return p.uniqueIdentifier(EPUBOptions{})
+}
+
+func (p Publication) uniqueIdentifier(opts EPUBOptions) string {
+	i := p.Identifier.Primary()
+	if i < 0 {
+		return ""
+	}
+	return opts.id("id", i, len(p.Identifier))
+}
+
+// Package return EPUB3 package with publication metadata and
+// unique-identifier. The manifest contains the cover image items only, other
+// items and spine are left to the caller.
+func (p Publication) Package() *epub.Package {
+	return p.PackageWithOptions(EPUBOptions{})
+}
+
+// PackageWithOptions return EPUB3 package with publication metadata
+// converted with options.
+func (p Publication) PackageWithOptions(opts EPUBOptions) *epub.Package {
+	var pkg = &epub.Package{
+		Version:          "3.0",
+		UniqueIdentifier: p.uniqueIdentifier(opts),
+		Lang:             p.Language,
+		Metadata:         p.EPUBWithOptions(opts),
+	}
+	pkg.Prefix, _ = EPUBPrefix(pkg.Metadata) // unknown prefixes are reported by Validate
+	if p.CoverImage != nil {
+		pkg.Manifest.Items = p.CoverImage.Items()
+	}
+	switch p.PageDirection {
+	case "ltr", "rtl":
+		pkg.Spine.PageDirection = p.PageDirection
+	}
+	return pkg
+}
+
+// EPUB return converted to EPUB3 Metadata data.
+func (p Publication) EPUB() epub.Metadata {
+	return p.EPUBWithOptions(EPUBOptions{})
+}
+
+// EPUBWithOptions return converted to EPUB3 Metadata data with options.
+func (p Publication) EPUBWithOptions(opts EPUBOptions) (meta epub.Metadata) {
+	meta.DC = "http://purl.org/dc/elements/1.1/" // add namespace
+	generateID := opts.id
 
 	// identifiers
 	for i, identifier := range p.Identifier {
@@ -102,27 +264,40 @@ func (p Publication) EPUB() (meta epub.Metadata) {
 		meta.Identifier = append(meta.Identifier, epub.Element{
 			Value: identifier.Text, ID: id})
 
-		if identifier.Scheme != "" {
+		if identifier.Scheme == "" {
+			continue
+		}
+		switch opts.IdentifierType {
+		case "", "onix":
 			meta.Meta = append(meta.Meta, epub.Meta{
 				Refines:  id,
 				Property: "identifier-type",
 				Scheme:   "onix:codelist5",
 				Value:    identifier.Onix(),
 			})
+		case "scheme":
+			meta.Meta = append(meta.Meta, epub.Meta{
+				Refines:  id,
+				Property: "identifier-type",
+				Value:    identifier.Scheme,
+			})
 		}
 	}
 
 	// titles
 	for i, title := range p.Title {
+		var fileAs = title.FileAs != "" && !opts.SkipFileAs
+		var titleType = title.Type != "" && !(title.Type == "main" && opts.SkipMainTitleType)
+
 		var id string
-		if title.FileAs != "" || title.Type != "" {
+		if fileAs || titleType {
 			id = generateID("title", i, len(p.Title))
 		}
 
 		meta.Title = append(meta.Title, epub.ElementLang{
-			Value: title.Text, ID: id})
+			Value: title.Text, ID: id, Lang: title.Lang})
 
-		if title.Type != "" {
+		if titleType {
 			meta.Meta = append(meta.Meta, epub.Meta{
 				Refines:  id,
 				Property: "title-type",
@@ -130,7 +305,7 @@ func (p Publication) EPUB() (meta epub.Metadata) {
 			})
 		}
 
-		if title.FileAs != "" {
+		if fileAs {
 			meta.Meta = append(meta.Meta, epub.Meta{
 				Refines:  id,
 				Property: "file-as",
@@ -149,19 +324,9 @@ func (p Publication) EPUB() (meta epub.Metadata) {
 		meta.Date = &epub.Element{Value: string(p.Date)}
 	}
 
-	// creators
-	for i, creator := range p.Creator {
-		role := creator.MARC()
-
-		var id string
-		if creator.FileAs != "" || role != "" {
-			id = generateID("creator", i, len(p.Creator))
-		}
-
-		meta.Creator = append(meta.Creator, epub.ElementLang{
-			Value: creator.Text, ID: id})
-
-		if role != "" {
+	// authors refinements
+	refineAuthor := func(id string, author Author) {
+		for _, role := range author.Relators(p.Language) {
 			meta.Meta = append(meta.Meta, epub.Meta{
 				Refines:  id,
 				Property: "role",
@@ -170,43 +335,53 @@ func (p Publication) EPUB() (meta epub.Metadata) {
 			})
 		}
 
-		if creator.FileAs != "" {
+		if author.FileAs != "" && !opts.SkipFileAs {
 			meta.Meta = append(meta.Meta, epub.Meta{
 				Refines:  id,
 				Property: "file-as",
-				Value:    creator.FileAs,
+				Value:    author.FileAs,
 			})
 		}
+
+		if author.DisplaySeq > 0 {
+			meta.Meta = append(meta.Meta, epub.Meta{
+				Refines:  id,
+				Property: "display-seq",
+				Value:    strconv.Itoa(author.DisplaySeq),
+			})
+		}
+	}
+
+	// modified
+	if modified, ok := p.Modified.EPUBModified(); ok {
+		meta.Meta = append(meta.Meta, epub.Meta{
+			Property: "dcterms:modified",
+			Value:    modified,
+		})
+	}
+
+	// creators
+	for i, creator := range p.Creator {
+		var id string
+		if creator.hasRefinements() {
+			id = generateID("creator", i, len(p.Creator))
+		}
+
+		meta.Creator = append(meta.Creator, epub.ElementLang{
+			Value: creator.Text, ID: id})
+		refineAuthor(id, creator)
 	}
 
 	// contributors
 	for i, contributor := range p.Contributor {
-		role := contributor.MARC()
-
 		var id string
-		if contributor.FileAs != "" || role != "" {
+		if contributor.hasRefinements() {
 			id = generateID("contributor", i, len(p.Contributor))
 		}
 
 		meta.Contributor = append(meta.Contributor, epub.ElementLang{
 			Value: contributor.Text, ID: id})
-
-		if role != "" {
-			meta.Meta = append(meta.Meta, epub.Meta{
-				Refines:  id,
-				Property: "role",
-				Scheme:   "marc:relators",
-				Value:    role,
-			})
-		}
-
-		if contributor.FileAs != "" {
-			meta.Meta = append(meta.Meta, epub.Meta{
-				Refines:  id,
-				Property: "file-as",
-				Value:    contributor.FileAs,
-			})
-		}
+		refineAuthor(id, contributor)
 	}
 
 	// subjects
@@ -216,8 +391,10 @@ func (p Publication) EPUB() (meta epub.Metadata) {
 
 	// description
 	if p.Description != "" {
-		// remove new line & spaces
-		descripion := strings.Join(strings.Fields(p.Description), " ")
+		descripion := p.DescriptionText()
+		if opts.KeepDescription {
+			descripion = p.DescriptionHTML()
+		}
 		meta.Description = []epub.ElementLang{{Value: descripion}}
 	}
 
@@ -252,8 +429,32 @@ func (p Publication) EPUB() (meta epub.Metadata) {
 	}
 
 	// rights
-	if p.Rights != "" {
-		meta.Rights = []epub.ElementLang{{Value: p.Rights}}
+	if rights := p.RightsText(); rights != "" {
+		meta.Rights = []epub.ElementLang{{Value: rights}}
+	}
+	if p.CopyrightHolder != "" {
+		meta.Meta = append(meta.Meta, epub.Meta{
+			Property: "dcterms:rightsHolder",
+			Value:    p.CopyrightHolder,
+		})
+	}
+	if p.CopyrightYear != 0 {
+		meta.Meta = append(meta.Meta, epub.Meta{
+			Property: "dcterms:dateCopyrighted",
+			Value:    strconv.Itoa(p.CopyrightYear),
+		})
+	}
+	if license := p.license(); license.LicenseURL() != "" {
+		meta.Link = append(meta.Link, epub.Link{
+			Rel:  "dcterms:license",
+			Href: license.LicenseURL(),
+		})
+		if license.IsCC() {
+			meta.Link = append(meta.Link, epub.Link{
+				Rel:  "cc:license",
+				Href: license.LicenseURL(),
+			})
+		}
 	}
 
 	// collection
@@ -280,24 +481,75 @@ func (p Publication) EPUB() (meta epub.Metadata) {
 		}
 	}
 
-	// ibooks
-	if p.IBooks != nil {
-		// version
-		if p.IBooks.Version != "" {
-			meta.Meta = append(meta.Meta, epub.Meta{
-				Property: "ibooks:version",
-				Value:    string(p.IBooks.Version),
-			})
+	// layout
+	if p.Layout != "" {
+		meta.Meta = append(meta.Meta, epub.Meta{
+			Property: "rendition:layout",
+			Value:    p.Layout,
+		})
+	}
+
+	// media overlays
+	if p.Duration != "" {
+		meta.Meta = append(meta.Meta, epub.Meta{
+			Property: "media:duration",
+			Value:    p.Duration.Clock(),
+		})
+	}
+	for _, track := range p.ReadingOrder {
+		// the duration refines the manifest item, so it is emitted for
+		// tracks with defined id only
+		if track.Duration == "" || track.ID == "" {
+			continue
 		}
+		meta.Meta = append(meta.Meta, epub.Meta{
+			Refines:  track.ID,
+			Property: "media:duration",
+			Value:    track.Duration.Clock(),
+		})
+	}
+	for _, authors := range []Authors{p.Creator, p.Contributor} {
+		for _, author := range authors {
+			for _, role := range author.Relators(p.Language) {
+				if role == "nrt" {
+					meta.Meta = append(meta.Meta, epub.Meta{
+						Property: "media:narrator",
+						Value:    author.Text,
+					})
+					break
+				}
+			}
+		}
+	}
+	if p.Abridged {
+		meta.Meta = append(meta.Meta, epub.Meta{
+			Property: "schema:abridged",
+			Value:    "true",
+		})
+	}
 
-		// specified fonts
-		if p.IBooks.SpecifiedFonts {
-			meta.Meta = append(meta.Meta, epub.Meta{
-				Property: "ibooks:specified-fonts",
-				Value:    "yes",
-			})
+	// vendors
+	var layout = p.Layout
+	if p.IBooks != nil {
+		meta.Meta = append(meta.Meta, p.IBooks.epub()...)
+	}
+	if p.Kobo != nil {
+		meta.Meta = append(meta.Meta, p.Kobo.epub(layout)...)
+		if p.Kobo.FixedLayout && layout == "" {
+			layout = "pre-paginated"
 		}
 	}
+	if p.Kindle != nil {
+		meta.Meta = append(meta.Meta, p.Kindle.epub(layout)...)
+	}
 
+	// extension properties
+	metas, links := p.extensionsEPUB()
+	meta.Meta = append(meta.Meta, metas...)
+	meta.Link = append(meta.Link, links...)
+
+	if opts.PostProcess != nil {
+		opts.PostProcess(&meta)
+	}
 	return
 }
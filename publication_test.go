@@ -1,10 +1,21 @@
 package metadata
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
 	"encoding/xml"
+	"image"
+	"image/png"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	epub "github.com/mdigger/epub3"
 	"gopkg.in/yaml.v3"
 )
 
@@ -66,3 +77,1602 @@ subject:
 
 	println()
 }
+
+func TestAuthorRoles(t *testing.T) {
+	data := `---
+creator:
+- role: [author, illustrator]
+  text: John Smith
+  display-seq: 2
+- role: editor
+  text: Sarah Jones
+  display-seq: 1
+...`
+
+	pub, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if codes := pub.Creator[0].MARCs(); len(codes) != 2 ||
+		codes[0] != "aut" || codes[1] != "ill" {
+		t.Errorf("bad roles: %v", codes)
+	}
+
+	if sorted := pub.Creator.Sorted(); sorted[0].Text != "Sarah Jones" {
+		t.Errorf("bad display order: %v", sorted)
+	}
+
+	var roles int
+	for _, meta := range pub.EPUB().Meta {
+		if meta.Property == "role" && meta.Refines == "pub-creator-01" {
+			roles++
+		}
+	}
+	if roles != 2 {
+		t.Errorf("bad role refinements count: %d", roles)
+	}
+
+	out, err := yaml.Marshal(Authors{{Role: Strings{"editor"}, Text: "Sarah Jones"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "role: editor\ntext: Sarah Jones\n" {
+		t.Errorf("bad compact form: %q", out)
+	}
+}
+
+func TestRelators(t *testing.T) {
+	for role, code := range map[string]string{
+		"aut":        "aut",
+		"Author":     "aut",
+		"Автор":      "aut",
+		"Übersetzer": "trl",
+		"ilustrador": "ill",
+	} {
+		if c, ok := LookupRelator(role, "ru-RU"); !ok || c != code {
+			t.Errorf("bad relator for %q: %q", role, c)
+		}
+	}
+
+	if label := RelatorLabel("trl", "ru"); label != "переводчик" {
+		t.Errorf("bad localized label: %q", label)
+	}
+
+	if onix := OnixRole("trl"); onix != "B06" {
+		t.Errorf("bad onix role: %q", onix)
+	}
+
+	pub := Publication{Creator: Authors{{Role: Strings{"autor"}, Text: "John Smith"},
+		{Role: Strings{"illustrater"}, Text: "Sarah Jones"}}}
+	warnings := pub.Validate()
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Message, `"illustrator"`) {
+		t.Errorf("bad warnings: %v", warnings)
+	}
+}
+
+func TestMARCWriter(t *testing.T) {
+	pub := Publication{
+		Identifier:  Identifiers{{Scheme: "ISBN-13", Text: "978-3-16-148410-0"}},
+		Title:       Titles{{Type: "main", Text: "My Book"}},
+		Language:    "en",
+		Date:        "2021-05-01",
+		Creator:     Authors{{Role: Strings{"author"}, Text: "John Smith"}},
+		Publisher:   "My Press",
+		Description: "About metadata.",
+	}
+	record := pub.MARC21()
+	if field := pub.marc008(time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC)); len(field) != 40 ||
+		field[:6] != "240309" || field[6:11] != "s2021" || field[35:38] != "eng" {
+		t.Errorf("bad 008 field: %q", field)
+	}
+	if field := record.Control("008"); field[:6] != "210501" {
+		t.Errorf("bad date entered on file: %q", field)
+	}
+	pub.Modified = "2022-02-03T10:00:00Z"
+	if field := pub.MARC21().Control("008"); field[:6] != "220203" {
+		t.Errorf("modification date not used: %q", field)
+	}
+	pub.Modified = ""
+	if isbn := record.Field("020"); len(isbn) != 1 || isbn[0].Subfield("a") != "9783161484100" {
+		t.Errorf("bad ISBN field: %v", isbn)
+	}
+
+	data, err := record.ISO2709()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[len(data)-1] != marcRecordTerminator {
+		t.Fatal("missed record terminator")
+	}
+	length, _ := strconv.Atoi(string(data[0:5]))
+	base, _ := strconv.Atoi(string(data[12:17]))
+	if length != len(data) || string(data[5:12]) != "nam a22" || string(data[17:24]) != " i 4500" {
+		t.Fatalf("bad leader: %q", data[:24])
+	}
+	if data[base-1] != marcFieldTerminator || (base-24-1)%12 != 0 {
+		t.Fatalf("bad base address: %d", base)
+	}
+	var entries = (base - 24 - 1) / 12
+	if entries != len(record.ControlFields)+len(record.DataFields) {
+		t.Fatalf("bad directory entries count: %d", entries)
+	}
+	for i := 0; i < entries; i++ {
+		entry := string(data[24+i*12 : 36+i*12])
+		size, _ := strconv.Atoi(entry[3:7])
+		start, _ := strconv.Atoi(entry[7:12])
+		value := data[base+start : base+start+size]
+		if value[len(value)-1] != marcFieldTerminator {
+			t.Errorf("%s: missed field terminator", entry[:3])
+		}
+		if entry[:3] == "245" && string(value) != "10\x1faMy Book /\x1fcJohn Smith\x1e" {
+			t.Errorf("bad title field: %q", value)
+		}
+	}
+
+	pub.Description = "First **paragraph**\nline.\n\nSecond paragraph."
+	if notes := pub.MARC21().Field("520"); len(notes) != 2 ||
+		notes[0].Subfield("a") != "First paragraph line." {
+		t.Errorf("bad summary fields: %v", notes)
+	}
+	if data, err := pub.MARC21().ISO2709(); err != nil {
+		t.Fatal(err)
+	} else if got, err := ParseMARC(data); err != nil ||
+		got[0].Description != "First paragraph line.\n\nSecond paragraph." {
+		t.Errorf("bad summary round trip: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMARCXML(&buf, record); err != nil {
+		t.Fatal(err)
+	}
+	var collection MARCCollection
+	if err := xml.Unmarshal(buf.Bytes(), &collection); err != nil {
+		t.Fatal(err)
+	}
+	if len(collection.Records) != 1 {
+		t.Fatalf("bad records count: %d", len(collection.Records))
+	}
+	got := collection.Records[0]
+	got.XMLName = record.XMLName
+	if !reflect.DeepEqual(got, record) {
+		t.Errorf("MARCXML round trip:\n%+v\n%+v", got, record)
+	}
+}
+
+func TestMARCRoundTrip(t *testing.T) {
+	pub := &Publication{
+		Identifier: Identifiers{{Scheme: "ISBN-13", Text: "9783161484100"},
+			{Scheme: "DOI", Text: "10.1000/182"}},
+		Title: Titles{{Type: "main", Text: "My Book"},
+			{Type: "subtitle", Text: "An investigation of metadata"}},
+		Language:            "ru",
+		Date:                "2021",
+		Creator:             Authors{{Role: Strings{"author"}, Text: "John Smith", FileAs: "Smith, John"}},
+		Contributor:         Authors{{Role: Strings{"editor"}, Text: "Sarah Jones"}},
+		Subject:             Strings{"Metadata"},
+		Description:         "About metadata.",
+		Publisher:           "My Press",
+		BelongsToCollection: "Series",
+		GroupPosition:       "2",
+	}
+
+	data, err := pub.MARC21().ISO2709()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var xmlData bytes.Buffer
+	if err := WriteMARCXML(&xmlData, pub.MARC21()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, data := range [][]byte{data, xmlData.Bytes()} {
+		pubs, err := ParseMARC(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pubs) != 1 {
+			t.Fatalf("bad records count: %d", len(pubs))
+		}
+		got := pubs[0]
+		if got.Title.Main() != "My Book" ||
+			got.Title.Subtitle() != "An investigation of metadata" {
+			t.Errorf("bad titles: %v", got.Title)
+		}
+		if len(got.Creator) != 1 || got.Creator[0].Text != "John Smith" ||
+			got.Creator[0].MARC() != "aut" {
+			t.Errorf("bad creators: %v", got.Creator)
+		}
+		if len(got.Contributor) != 1 || got.Contributor[0].MARC() != "edt" {
+			t.Errorf("bad contributors: %v", got.Contributor)
+		}
+		if len(got.Identifier) != 2 || got.Identifier[1].Scheme != "DOI" {
+			t.Errorf("bad identifiers: %v", got.Identifier)
+		}
+		if got.Language != "ru" || got.Date != "2021" || got.Publisher != "My Press" ||
+			got.BelongsToCollection != "Series" || got.GroupPosition != "2" {
+			t.Errorf("bad publication: %+v", got)
+		}
+		if _, ok := got.Properties["marc:001"]; !ok {
+			t.Errorf("control number not retained: %v", got.Properties)
+		}
+	}
+}
+
+func TestReadium(t *testing.T) {
+	pub := &Publication{
+		Identifier: Identifiers{{Scheme: "ISBN-13", Text: "urn:isbn:9783161484100"}},
+		Title: Titles{{Type: "main", Text: "My Book", FileAs: "Book, My"},
+			{Type: "main", Text: "Mon livre", Lang: "fr"}},
+		Language:            "en",
+		Date:                "2021-01-02",
+		Creator:             Authors{{Role: Strings{"author", "illustrator"}, Text: "John Smith"}},
+		Contributor:         Authors{{Role: Strings{"translator"}, Text: "Sarah Jones"}},
+		Subject:             Strings{"Metadata"},
+		Publisher:           "My Press",
+		BelongsToCollection: "Series",
+		GroupPosition:       "2",
+		PageDirection:       "rtl",
+		Layout:              "pre-paginated",
+	}
+
+	data, err := json.Marshal(pub.Readium())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"author", "illustrator", "translator",
+		"publisher", "belongsTo", "readingProgression", "presentation"} {
+		if _, ok := obj[key]; !ok {
+			t.Errorf("%s not defined: %s", key, data)
+		}
+	}
+
+	got, err := ParseReadium([]byte(`{"metadata":` + string(data) + `}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title.Main() != "My Book" || len(got.Title) != 2 || got.Title[0].FileAs != "Book, My" {
+		t.Errorf("bad titles: %v", got.Title)
+	}
+	if len(got.Creator) != 1 || len(got.Creator[0].MARCs()) != 2 {
+		t.Errorf("bad creators: %v", got.Creator)
+	}
+	if len(got.Contributor) != 1 || got.Contributor[0].MARC() != "trl" {
+		t.Errorf("bad contributors: %v", got.Contributor)
+	}
+	if got.BelongsToCollection != "Series" || got.GroupPosition != "2" ||
+		got.PageDirection != "rtl" || got.Layout != "pre-paginated" ||
+		got.Publisher != "My Press" {
+		t.Errorf("bad publication: %+v", got)
+	}
+
+	// publisher role of creator don't replace the publication publisher
+	pub = &Publication{
+		Creator:   Authors{{Role: Strings{"pbl"}, Text: "Pub Author"}},
+		Publisher: "House",
+	}
+	if data, err = json.Marshal(pub.Readium()); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = ParseReadium([]byte(`{"metadata":` + string(data) + `}`)); err != nil {
+		t.Fatal(err)
+	}
+	var authors = append(got.Creator, got.Contributor...)
+	if got.Publisher != "House" || len(authors) != 1 ||
+		authors[0].Text != "Pub Author" || authors[0].MARC() != "pbl" {
+		t.Errorf("bad publisher round trip: %s\n%+v", data, got)
+	}
+}
+
+func TestOPDS(t *testing.T) {
+	pub := &Publication{
+		Identifier: Identifiers{{Scheme: "ISBN-13", Text: "978-3-16-148410-0"}},
+		Title:      Titles{{Type: "main", Text: "My Book"}},
+		Creator:    Authors{{Text: "John Smith", FileAs: "Smith, John"}},
+		Date:       "2021",
+	}
+	feed := OPDSFeed{
+		ID:    "urn:catalog",
+		Title: "Catalog",
+		Acquisition: func(pub *Publication) []OPDSLink {
+			return []OPDSLink{{Rel: OPDSAcquisition, Href: "book.epub"}}
+		},
+		Cover: func(pub *Publication) []OPDSLink {
+			return []OPDSLink{{Rel: OPDSImage, Href: "cover.jpg"}}
+		},
+	}
+
+	atom := feed.Atom([]*Publication{pub})
+	if entry := atom.Entries[0]; entry.ID != "urn:isbn:9783161484100" ||
+		entry.Updated != "2021-01-01T00:00:00Z" || len(entry.Links) != 2 {
+		t.Errorf("bad atom entry: %+v", entry)
+	}
+	data, err := xml.Marshal(atom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("<opds:sortAs>Smith, John</opds:sortAs>")) {
+		t.Errorf("author sort name not defined: %s", data)
+	}
+
+	opds2 := feed.JSON([]*Publication{pub})
+	if p := opds2.Publications[0]; len(p.Links) != 1 || len(p.Images) != 1 ||
+		p.Metadata.Identifier != "urn:isbn:9783161484100" {
+		t.Errorf("bad opds2 publication: %+v", p)
+	}
+	data, err = json.Marshal(opds2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte(`"sortAs":"Smith, John"`)) {
+		t.Errorf("author sort name not defined: %s", data)
+	}
+
+	uuid, err := Publication{Title: Titles{{Text: "My Book"}}}.UUID("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := Publication{Title: Titles{{Text: "My Book"}}}.OPDSEntry()
+	if entry.ID != "urn:uuid:"+uuid {
+		t.Errorf("bad entry id: %q", entry.ID)
+	}
+}
+
+func TestIdentifierURN(t *testing.T) {
+	for id, urn := range map[Identifier]string{
+		{Scheme: "ISBN-13", Text: "978-3-16-148410-0"}:                   "urn:isbn:9783161484100",
+		{Scheme: "ISBN-13", Text: "ISBN 978-5-17-000000-0"}:              "urn:isbn:9785170000000",
+		{Scheme: "ISBN-13", Text: "isbn:978-3-16-148410-0"}:              "urn:isbn:9783161484100",
+		{Scheme: "ISBN-10", Text: " 0 306 40615 2 "}:                     "urn:isbn:0306406152",
+		{Scheme: "ISBN-13", Text: "urn:isbn:9783161484100"}:              "urn:isbn:9783161484100",
+		{Scheme: "UUID", Text: "{f81d4fae-7dec-41d0-a765-00a0c91e6bf6}"}: "urn:uuid:f81d4fae-7dec-41d0-a765-00a0c91e6bf6",
+		{Scheme: "DOI", Text: "doi:10.1000/182"}:                         "urn:doi:10.1000/182",
+	} {
+		if got := id.URN(); got != urn {
+			t.Errorf("%s: bad URN %q", id.Text, got)
+		}
+	}
+}
+
+func TestEPUB2(t *testing.T) {
+	pub := Publication{
+		Identifier: Identifiers{{Scheme: "ISBN-13", Text: "9783161484100"},
+			{Scheme: "UUID", Text: "urn:uuid:f81d4fae-7dec-41d0-a765-00a0c91e6bf6"}},
+		Title:               Titles{{Type: "main", Text: "My Book"}},
+		Language:            "en",
+		Date:                "2021-05-01",
+		Modified:            "2021-06-01",
+		Creator:             Authors{{Role: Strings{"author"}, Text: "John Smith", FileAs: "Smith, John"}},
+		BelongsToCollection: "Series",
+		GroupPosition:       "2",
+	}
+
+	opf := pub.EPUB2()
+	if len(opf.Identifier) != 2 || opf.Identifier[0].Scheme != "ISBN" ||
+		opf.Identifier[1].Scheme != "UUID" || opf.Identifier[1].ID != pub.UniqueIdentifier() {
+		t.Errorf("bad identifiers: %+v", opf.Identifier)
+	}
+	if len(opf.Creator) != 1 || opf.Creator[0].Role != "aut" ||
+		opf.Creator[0].FileAs != "Smith, John" || opf.Creator[0].ID != "" {
+		t.Errorf("bad creators: %+v", opf.Creator)
+	}
+	if len(opf.Date) != 2 || opf.Date[1].Event != "modification" {
+		t.Errorf("bad dates: %+v", opf.Date)
+	}
+	for _, m := range opf.Meta {
+		if m.Property != "" {
+			t.Errorf("EPUB 3 meta in EPUB 2: %+v", m)
+		}
+	}
+	data, err := xml.Marshal(opf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte(`<meta name="calibre:series" content="Series"></meta>`)) {
+		t.Errorf("series not defined: %s", data)
+	}
+
+	hybrid := pub.EPUBHybrid()
+	if len(hybrid.Date) != 1 || hybrid.Creator[0].ID == "" || hybrid.Creator[0].Role != "aut" {
+		t.Errorf("bad hybrid elements: %+v %+v", hybrid.Date, hybrid.Creator)
+	}
+	var refines = make(map[string]bool)
+	for _, m := range hybrid.Meta {
+		refines[m.Property] = true
+	}
+	for _, property := range []string{"dcterms:modified", "role", "file-as", "identifier-type"} {
+		if !refines[property] {
+			t.Errorf("%s not defined in hybrid metadata: %+v", property, hybrid.Meta)
+		}
+	}
+}
+
+const calibreOPFData = `<?xml version='1.0' encoding='utf-8'?>
+<package xmlns="http://www.idpf.org/2007/opf" unique-identifier="uuid_id" version="2.0">
+    <metadata xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:opf="http://www.idpf.org/2007/opf">
+        <dc:identifier opf:scheme="calibre" id="calibre_id">42</dc:identifier>
+        <dc:identifier opf:scheme="uuid" id="uuid_id">02b1f386-e83a-4454-b6ec-422dd949be43</dc:identifier>
+        <dc:title>My Book</dc:title>
+        <dc:creator opf:file-as="Smith, John" opf:role="aut">John Smith</dc:creator>
+        <dc:contributor opf:file-as="calibre" opf:role="bkp">calibre (5.0.0)</dc:contributor>
+        <dc:date>2021-01-05T00:00:00+00:00</dc:date>
+        <dc:publisher>My Press</dc:publisher>
+        <dc:identifier opf:scheme="ISBN">9783161484100</dc:identifier>
+        <dc:language>eng</dc:language>
+        <dc:subject>Fiction</dc:subject>
+        <meta name="calibre:author_link_map" content="{&quot;John Smith&quot;: &quot;&quot;}"/>
+        <meta name="calibre:series" content="Series"/>
+        <meta name="calibre:series_index" content="2"/>
+        <meta name="calibre:rating" content="8"/>
+        <meta name="calibre:title_sort" content="Book, My"/>
+        <meta name="calibre:user_metadata:#genre" content="{&quot;datatype&quot;: &quot;text&quot;, &quot;#value#&quot;: &quot;SF&quot;}"/>
+    </metadata>
+    <guide>
+        <reference type="cover" title="Cover" href="cover.jpg"/>
+    </guide>
+</package>`
+
+func TestCalibre(t *testing.T) {
+	pub, err := ParseCalibre([]byte(calibreOPFData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pub.Identifier) != 3 || pub.Identifier[0].Scheme != "calibre" ||
+		pub.Identifier[0].Onix() != "01" || pub.Identifier[2].Scheme != "ISBN-13" {
+		t.Errorf("bad identifiers: %v", pub.Identifier)
+	}
+	if len(pub.Title) != 1 || pub.Title[0].FileAs != "Book, My" {
+		t.Errorf("bad title: %v", pub.Title)
+	}
+	if len(pub.Contributor) != 0 || pub.Creator[0].MARC() != "aut" {
+		t.Errorf("bad authors: %v %v", pub.Creator, pub.Contributor)
+	}
+	if pub.Date != "2021-01-05" || pub.Language != "en" || pub.CoverImage == nil || pub.CoverImage.Path != "cover.jpg" ||
+		pub.BelongsToCollection != "Series" || pub.GroupPosition != "2" {
+		t.Errorf("bad publication: %+v", pub)
+	}
+	if _, ok := pub.Properties["calibre:user_metadata:#genre"].(map[string]interface{}); !ok {
+		t.Errorf("bad custom column: %v", pub.Properties)
+	}
+
+	data, err := xml.Marshal(pub.Calibre())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseCalibre(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Properties["calibre:rating"] != 8.0 || got.Title[0].FileAs != "Book, My" ||
+		len(got.Identifier) != 3 || got.BelongsToCollection != "Series" {
+		t.Errorf("bad round trip: %+v", got)
+	}
+}
+
+func TestFB2(t *testing.T) {
+	data := `<?xml version="1.0" encoding="utf-8"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0" xmlns:l="http://www.w3.org/1999/xlink">
+<description>
+  <title-info>
+    <genre>sf_space</genre>
+    <author><first-name>Иван</first-name><middle-name>Петрович</middle-name><last-name>Сидоров</last-name></author>
+    <book-title>Книга</book-title>
+    <annotation><p>Первый абзац.</p><p>Второй абзац.</p></annotation>
+    <date value="2021-01-05">2021</date>
+    <lang>ru</lang>
+    <translator><first-name>John</first-name><last-name>Smith</last-name></translator>
+    <sequence name="Серия" number="2"/>
+  </title-info>
+  <document-info>
+    <id>02B1F386-E83A-4454-B6EC-422DD949BE43</id>
+    <version>1.1</version>
+  </document-info>
+  <publish-info>
+    <publisher>Издательство</publisher>
+    <isbn>978-3-16-148410-0</isbn>
+  </publish-info>
+</description>
+<body><section><p>Text</p></section></body>
+</FictionBook>`
+
+	pub, err := ParseFB2([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pub.Creator) != 1 || pub.Creator[0].Text != "Иван Петрович Сидоров" ||
+		pub.Creator[0].FileAs != "Сидоров, Иван Петрович" {
+		t.Errorf("bad creators: %v", pub.Creator)
+	}
+	if len(pub.Contributor) != 1 || pub.Contributor[0].MARC() != "trl" {
+		t.Errorf("bad contributors: %v", pub.Contributor)
+	}
+	if len(pub.Subject) != 1 || pub.Subject[0] != "Space SF" {
+		t.Errorf("bad subjects: %v", pub.Subject)
+	}
+	if pub.Description != "Первый абзац.\n\nВторой абзац." || pub.Date != "2021-01-05" ||
+		pub.BelongsToCollection != "Серия" || pub.GroupPosition != "2" ||
+		pub.Version != "1.1.0" || len(pub.Identifier) != 2 {
+		t.Errorf("bad publication: %+v", pub)
+	}
+
+	fb2 := pub.FB2()
+	if len(fb2.TitleInfo.Genre) != 1 || fb2.TitleInfo.Genre[0] != "sf_space" ||
+		fb2.TitleInfo.Author[0].LastName != "Сидоров" ||
+		len(fb2.TitleInfo.Annotation.Paragraphs) != 2 ||
+		fb2.PublishInfo.ISBN != "978-3-16-148410-0" {
+		t.Errorf("bad FB2 description: %+v", fb2)
+	}
+}
+
+func TestXMP(t *testing.T) {
+	pub := &Publication{
+		Identifier: Identifiers{{Scheme: "ISBN-13", Text: "9783161484100"},
+			{Scheme: "DOI", Text: "10.1000/182"}},
+		Title: Titles{{Type: "main", Text: "My Book"},
+			{Type: "main", Text: "Mon livre", Lang: "fr"}},
+		Creator:    Authors{{Text: "John Smith"}, {Text: "Sarah Jones"}},
+		Subject:    Strings{"Metadata", "XMP"},
+		Rights:     "© 2007 John Smith",
+		Date:       "2021-01-05",
+		Modified:   "2021-02-01T10:00:00Z",
+		Properties: map[string]interface{}{"edition": "First"},
+	}
+
+	packet := pub.XMP(&XMPOptions{PDFAPart: 2, PDFAConformance: "b"})
+	if !bytes.Contains(packet, []byte("<pdfaid:part>2</pdfaid:part>")) {
+		t.Errorf("PDF/A identification not defined:\n%s", packet)
+	}
+
+	pdf := append([]byte("%PDF-1.7\n1 0 obj\n<< /Type /Metadata >>\nstream\n"), packet...)
+	got, err := ParseXMP(append(pdf, "\nendstream\n"...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Title) != 2 || got.Title.Main() != "My Book" || got.Title[1].Lang != "fr" {
+		t.Errorf("bad titles: %v", got.Title)
+	}
+	if len(got.Creator) != 2 || len(got.Subject) != 2 || got.Rights != pub.Rights {
+		t.Errorf("bad publication: %+v", got)
+	}
+	if len(got.Identifier) != 2 || got.Identifier[1].Scheme != "DOI" {
+		t.Errorf("bad identifiers: %v", got.Identifier)
+	}
+	if got.Date != pub.Date || got.Modified != pub.Modified ||
+		got.Properties["edition"] != "First" {
+		t.Errorf("bad publication: %+v", got)
+	}
+}
+
+func TestOffice(t *testing.T) {
+	pub := &Publication{
+		Identifier: Identifiers{{Scheme: "ISBN-13", Text: "9783161484100"}},
+		Title:      Titles{{Type: "main", Text: "My Book"}},
+		Creator:    Authors{{Text: "John Smith"}, {Text: "Sarah Jones"}},
+		Subject:    Strings{"Metadata", "Office"},
+		Language:   "en",
+		Date:       "2021-01-05",
+		Properties: map[string]interface{}{"edition": "First"},
+	}
+
+	dir := t.TempDir()
+	for name, fixture := range map[string]struct{ part, data, keep string }{
+		"book.docx": {OOXMLCorePart, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" xmlns:dcmitype="http://purl.org/dc/dcmitype/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"><dc:title>Draft</dc:title><dc:creator>Editor</dc:creator><cp:lastModifiedBy>Editor</cp:lastModifiedBy><cp:revision>7</cp:revision><dcterms:created xsi:type="dcterms:W3CDTF">2020-01-01T00:00:00Z</dcterms:created></cp:coreProperties>`,
+			"<cp:lastModifiedBy>Editor</cp:lastModifiedBy><cp:revision>7</cp:revision>"},
+		"book.odt": {ODFMetaPart, `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-meta xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:meta="urn:oasis:names:tc:opendocument:xmlns:meta:1.0" xmlns:dc="http://purl.org/dc/elements/1.1/" office:version="1.2">
+ <office:meta>
+  <meta:generator>LibreOffice</meta:generator>
+  <dc:title>Draft</dc:title>
+  <meta:editing-cycles>7</meta:editing-cycles>
+  <meta:document-statistic meta:page-count="1" meta:word-count="2"/>
+  <meta:user-defined meta:name="status">Draft</meta:user-defined>
+ </office:meta>
+</office:document-meta>`,
+			`<meta:document-statistic meta:page-count="1" meta:word-count="2"/>`},
+	} {
+		src := filepath.Join(dir, name)
+		f, err := os.Create(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		z := zip.NewWriter(f)
+		w, err := z.Create(fixture.part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(fixture.data))
+		z.Close()
+		f.Close()
+
+		dst := filepath.Join(dir, "stamped-"+name)
+		if err := pub.StampOffice(src, dst); err != nil {
+			t.Fatal(err)
+		}
+		got, err := LoadOffice(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Title.Main() != "My Book" || len(got.Creator) != 2 ||
+			len(got.Subject) != 2 || got.Language != "en" || got.Date != "2021-01-05" {
+			t.Errorf("%s: bad publication: %+v", name, got)
+		}
+
+		r, err := zip.OpenReader(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part, err := r.File[0].Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var data bytes.Buffer
+		data.ReadFrom(part)
+		r.Close()
+		if !strings.Contains(data.String(), fixture.keep) ||
+			strings.Contains(data.String(), "<dc:title>Draft") {
+			t.Errorf("%s: properties not merged:\n%s", name, data.String())
+		}
+		if strings.HasSuffix(name, ".odt") && (!strings.Contains(data.String(), `meta:name="status"`) ||
+			!strings.Contains(data.String(), `meta:name="edition"`)) {
+			t.Errorf("%s: user defined fields not merged:\n%s", name, data.String())
+		}
+	}
+}
+
+func TestAudiobook(t *testing.T) {
+	data := []byte(`
+title: My Book
+creator: John Smith
+contributor:
+  - text: Jane Doe
+    role: narrator
+duration: PT1H30M5.5S
+abridged: true
+reading-order:
+  - id: ch1
+    href: ch1.MP3
+    title: Chapter 1
+    duration: PT45M
+  - href: ch2.mp3
+    title: Chapter 2
+    duration: PT45M5.5S
+`)
+	pub, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warnings := pub.Validate(); len(warnings) > 0 {
+		t.Error(warnings)
+	}
+	if clock := pub.Duration.Clock(); clock != "1:30:05.500" {
+		t.Errorf("bad clock value: %v", clock)
+	}
+
+	var narrator bool
+	var durations []string
+	for _, meta := range pub.EPUB().Meta {
+		if meta.Property == "media:narrator" && meta.Value == "Jane Doe" {
+			narrator = true
+		}
+		if meta.Property == "media:duration" && meta.Refines != "" {
+			durations = append(durations, meta.Refines)
+		}
+	}
+	if !narrator {
+		t.Error("media:narrator not found")
+	}
+	if len(durations) != 1 || durations[0] != "ch1" {
+		t.Errorf("bad track durations refines: %v", durations)
+	}
+
+	manifest := pub.Audiobook()
+	if len(manifest.ReadBy) != 1 || len(manifest.ReadingOrder) != 2 ||
+		manifest.ReadingOrder[0].EncodingFormat != "audio/mpeg" {
+		t.Errorf("bad manifest: %+v", manifest)
+	}
+
+	pub.Duration = "PT2H"
+	if warnings := pub.Validate(); len(warnings) != 1 {
+		t.Errorf("expected duration warning: %v", warnings)
+	}
+	if _, err := Parse([]byte("duration: 1:30:00")); err == nil {
+		t.Error("expected duration format error")
+	}
+}
+
+func TestComicInfo(t *testing.T) {
+	pub := &Publication{
+		Identifier: Identifiers{{Scheme: "ISBN-13", Text: "978-3-16-148410-0"}},
+		Title:      Titles{{Type: "main", Text: "My Manga"}},
+		Creator: Authors{{Text: "John Smith"},
+			{Text: "Sarah Jones", Role: Strings{"artist", "illustrator", "cover designer"}}},
+		Contributor: Authors{{Text: "Ann Lee", Role: Strings{"comicinfo:Inker"}},
+			{Text: "Bob Brown", Role: Strings{"trl"}}},
+		BelongsToCollection: "My Series",
+		GroupPosition:       "3",
+		Language:            "ja",
+		Date:                "2021-01-05",
+		PageDirection:       "rtl",
+		Subject:             Strings{"Action", "Comedy"},
+		Properties:          map[string]interface{}{"comicinfo:Volume": 2, "comicinfo:AgeRating": "Teen"},
+	}
+	if warnings := pub.Validate(); len(warnings) > 0 {
+		t.Error(warnings)
+	}
+
+	data, err := xml.MarshalIndent(pub.ComicInfo(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"<Writer>John Smith</Writer>",
+		"<Penciller>Sarah Jones</Penciller>",
+		"<CoverArtist>Sarah Jones</CoverArtist>",
+		"<Inker>Ann Lee</Inker>",
+		"<Volume>2</Volume>",
+		"<Manga>YesAndRightToLeft</Manga>",
+		"<GTIN>9783161484100</GTIN>",
+		"<AgeRating>Teen</AgeRating>",
+	} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("%s not found", s)
+		}
+	}
+
+	got, err := ParseComicInfo(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.PageDirection != "rtl" || got.Date != "2021-01-05" || got.GroupPosition != "3" ||
+		len(got.Creator) != 2 || len(got.Contributor) != 2 ||
+		got.Identifier[0].Scheme != "ISBN-13" || got.Properties["comicinfo:AgeRating"] != "Teen" ||
+		got.Contributor[0].Role[0] != "comicinfo:Inker" || got.Properties["comicinfo:Volume"] != "2" {
+		t.Errorf("bad publication: %+v", got)
+	}
+
+	// volume is defined by property only
+	delete(pub.Properties, "comicinfo:Volume")
+	if ci := pub.ComicInfo(); ci.Volume != "" || ci.Number != "3" {
+		t.Errorf("bad volume: %q", ci.Volume)
+	}
+	got = ComicInfo{Volume: "4"}.Publication()
+	if got.GroupPosition != "" || got.Properties["comicinfo:Volume"] != "4" {
+		t.Errorf("bad volume position: %+v", got)
+	}
+
+	// role labels are accepted with comicinfo prefix only
+	pub.Contributor[0].Role = Strings{"inker"}
+	if warnings := pub.Validate(); len(warnings) != 1 {
+		t.Errorf("expected role warning: %v", warnings)
+	}
+}
+
+func TestDOI(t *testing.T) {
+	pub := &Publication{
+		Identifier: Identifiers{{Scheme: "DOI", Text: "https://doi.org/10.1000/182"},
+			{Scheme: "ISBN-13", Text: "urn:isbn:978-3-16-148410-0"}},
+		Title:       Titles{{Type: "main", Text: "My Book"}, {Type: "subtitle", Text: "A Study"}},
+		Creator:     Authors{{Text: "John Smith"}},
+		Contributor: Authors{{Text: "Sarah Jones", Role: Strings{"edt"}}},
+		Publisher:   "My Press",
+		Date:        "2021-01-05",
+		Language:    "en",
+	}
+	batch, err := pub.Crossref(DOIDeposit{
+		DepositorName:  "My Press",
+		DepositorEmail: "doi@example.com",
+		Registrant:     "My Press",
+		URL:            "https://example.com/my-book",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := xml.MarshalIndent(batch, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"<doi>10.1000/182</doi>",
+		`<person_name sequence="first" contributor_role="author">`,
+		"<surname>Smith</surname>",
+		`<isbn media_type="electronic">9783161484100</isbn>`,
+		"<month>01</month>",
+	} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("%s not found in:\n%s", s, data)
+		}
+	}
+
+	res, err := pub.DataCite()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Identifier.Value != "10.1000/182" || res.PublicationYear != "2021" ||
+		len(res.Contributors) != 1 || res.Contributors[0].Type != "Editor" {
+		t.Errorf("bad DataCite resource: %+v", res)
+	}
+	if _, err := xml.Marshal(res); err != nil {
+		t.Error(err)
+	}
+
+	pub.Identifier[0].Text = "10.1000"
+	if _, err := pub.DataCite(); err == nil {
+		t.Error("expected bad DOI error")
+	}
+	if warnings := pub.Validate(); len(warnings) != 1 {
+		t.Errorf("expected DOI warning: %v", warnings)
+	}
+}
+
+func TestCitations(t *testing.T) {
+	pubs := []*Publication{{
+		Identifier: Identifiers{{Scheme: "ISBN-13", Text: "978-3-16-148410-0"},
+			{Scheme: "DOI", Text: "doi:10.1000/182"}},
+		Title:       Titles{{Type: "main", Text: "My Book"}},
+		Creator:     Authors{{Text: "John Smith"}},
+		Contributor: Authors{{Text: "Sarah Jones", Role: Strings{"edt"}}},
+		Publisher:   "My Press",
+		Date:        "2021-01-05",
+		Language:    "en",
+		Subject:     Strings{"Metadata", "Citations"},
+		Description: "Long\ndescription.",
+	}, {
+		Title:   Titles{{Type: "main", Text: "My Thesis"}},
+		Creator: Authors{{Text: "Ann Lee", FileAs: "Lee, Ann"}},
+		Type:    "thesis",
+		Date:    "2020",
+	}}
+
+	check := func(format string, got []*Publication) {
+		if len(got) != 2 {
+			t.Fatalf("%s: bad records count: %d", format, len(got))
+		}
+		if pub := got[0]; pub.Title.Main() != "My Book" || pub.Date != "2021-01-05" ||
+			len(pub.Identifier) != 2 || pub.Identifier[1].Text != "10.1000/182" ||
+			pub.Creator[0].Text != "John Smith" || pub.Contributor[0].MARC() != "edt" ||
+			len(pub.Subject) != 2 || pub.Description != "Long description." {
+			t.Errorf("%s: bad publication: %+v", format, pub)
+		}
+		if pub := got[1]; pub.Type != "thesis" || pub.Date != "2020" ||
+			pub.Creator[0].FileAs != "Lee, Ann" {
+			t.Errorf("%s: bad publication: %+v", format, pub)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteRIS(&buf, pubs...); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "DA  - 2021/01/05/\r\n") {
+		t.Errorf("bad RIS:\n%s", buf.String())
+	}
+	got, err := ParseRIS(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("RIS", got)
+
+	buf.Reset()
+	if err := WriteEndNote(&buf, pubs...); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = ParseEndNote(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	check("EndNote", got)
+
+	got, err = ParseEndNote([]byte(`<xml><records><record>
+<ref-type name="Book">6</ref-type>
+<titles><title><style face="normal" font="default" size="100%">Styled</style></title></titles>
+</record></records></xml>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].Title.Main() != "Styled" {
+		t.Errorf("bad styled title: %v", got[0].Title)
+	}
+}
+
+func TestUUID(t *testing.T) {
+	// RFC 4122 DNS namespace test vector
+	uuid, err := NewUUIDv5("6ba7b810-9dad-11d1-80b4-00c04fd430c8", "python.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uuid != "886313e1-3b8a-5372-9b90-0c9aee199e5d" {
+		t.Errorf("bad UUIDv5: %v", uuid)
+	}
+
+	for isbn, digits := range map[string]string{
+		"3-16-148410-X":          "9783161484100",
+		"ISBN 978-3-16-148410-0": "9783161484100",
+		"urn:isbn:316148410X":    "9783161484100",
+		"12X4567890":             "",
+		"X234567890":             "",
+		"978316148410X":          "",
+		"12345":                  "",
+	} {
+		if got := isbn13(isbn); got != digits {
+			t.Errorf("%s: bad ISBN-13 %q", isbn, got)
+		}
+	}
+
+	// ISBN-10 and ISBN-13 give the same UUID
+	isbn10, _ := Publication{Identifier: Identifiers{{Scheme: "ISBN-10", Text: "3-16-148410-X"}}}.UUID("")
+	isbn13, _ := Publication{Identifier: Identifiers{{Scheme: "ISBN-13", Text: "978-3-16-148410-0"}}}.UUID("")
+	if isbn10 == "" || isbn10 != isbn13 {
+		t.Errorf("ISBN UUID mismatch: %v != %v", isbn10, isbn13)
+	}
+	raw, _ := Publication{Identifier: Identifiers{{Scheme: "ISBN-10", Text: "12X4567890"}}}.UUID("")
+	if want, _ := NewUUIDv5(UUIDNamespace, "12X4567890"); raw != want {
+		t.Errorf("not ISBN text UUID: %v != %v", raw, want)
+	}
+
+	data := []byte("title: My Book\ncreator: John Smith\n")
+	pub, err := ParseWithOptions(data, ParseOptions{AssignUUID: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := ParseWithOptions(data, ParseOptions{AssignUUID: true})
+	if len(pub.Identifier) != 1 || pub.Identifier[0].Scheme != "UUID" ||
+		!strings.HasPrefix(pub.Identifier[0].Text, "urn:uuid:") ||
+		pub.Identifier[0].Text != again.Identifier[0].Text {
+		t.Errorf("bad assigned identifier: %v", pub.Identifier)
+	}
+	if warnings := pub.Validate(); len(warnings) > 0 {
+		t.Error(warnings)
+	}
+
+	// scaffolded YAML is stable
+	pub.Identifier = nil
+	out1, err := yaml.Marshal(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out2, _ := yaml.Marshal(pub)
+	if string(out1) != string(out2) || !strings.Contains(string(out1), "# urn:uuid:") {
+		t.Errorf("unstable YAML:\n%s\n%s", out1, out2)
+	}
+
+	pub.Identifier = Identifiers{{Scheme: "UUID", Text: "urn:uuid:886313e1-3b8a-0372-9b90-0c9aee199e5d"}}
+	if warnings := pub.Validate(); len(warnings) != 1 {
+		t.Errorf("expected UUID version warning: %v", warnings)
+	}
+}
+
+func TestDetectScheme(t *testing.T) {
+	for text, scheme := range map[string]string{
+		"urn:uuid:886313e1-3b8a-5372-9b90-0c9aee199e5d": "UUID",
+		"{886313E1-3B8A-5372-9B90-0C9AEE199E5D}":        "UUID",
+		"978-3-16-148410-0":                             "ISBN-13",
+		"9783161484100":                                 "ISBN-13",
+		"urn:isbn:9783161484100":                        "ISBN-13",
+		"3-16-148410-X":                                 "ISBN-10",
+		"979-0-2600-0043-8":                             "ISMN-13",
+		"M-2306-7118-7":                                 "ISMN-10",
+		"0317-8471":                                     "ISSN",
+		"doi:10.1000/182":                               "DOI",
+		"https://doi.org/10.1000/182":                   "DOI",
+		"10.1000/182":                                   "DOI",
+		"ark:/13030/tf5p30086k":                         "ARK",
+		"hdl:2027/mdp.39015012345678":                   "Handle",
+		"lccn:2001000002":                               "LCCN",
+		"n78-890351":                                    "LCCN",
+		"(OCoLC)12345678":                               "OCLC",
+		"ocm12345678":                                   "OCLC",
+		"urn:nbn:de:101:1-201012":                       "URN",
+		"https://example.com/book":                      "URL",
+	} {
+		got, err := DetectScheme(text)
+		if err != nil || got != scheme {
+			t.Errorf("%s: got %q (%v), want %q", text, got, err, scheme)
+		}
+	}
+
+	for scheme, onix := range map[string]string{
+		"ARK": "35", "ISSN": "01", "Handle": "01", "URL": "01", "ISBN-13": "15",
+	} {
+		if got := (Identifier{Scheme: scheme}).Onix(); got != onix {
+			t.Errorf("%s: bad ONIX code %q, want %q", scheme, got, onix)
+		}
+	}
+
+	for _, text := range []string{"", "x", "-", "12345", "978-3-16-148410-1", "12-3", "2021-01"} {
+		if scheme, err := DetectScheme(text); err == nil {
+			t.Errorf("%q: unexpected scheme %q", text, scheme)
+		}
+	}
+	for _, data := range []string{`identifier: x`, `identifier: "-"`, `identifier: [a, b]`} {
+		if _, err := Parse([]byte(data)); err != nil {
+			t.Errorf("%s: %v", data, err)
+		}
+	}
+	if _, err := Parse([]byte(`identifier: [""]`)); err == nil {
+		t.Error("expected empty identifier error")
+	}
+
+	RegisterScheme("ACME", func(text string) bool { return strings.HasPrefix(text, "ACME-") })
+	defer func() { customSchemes = nil }()
+	if scheme, _ := DetectScheme("ACME-0001"); scheme != "ACME" {
+		t.Errorf("custom scheme not detected: %q", scheme)
+	}
+}
+
+func TestPrimaryIdentifier(t *testing.T) {
+	pub, err := Parse([]byte(`
+identifier:
+  - 978-3-16-148410-0
+  - urn:uuid:886313e1-3b8a-5372-9b90-0c9aee199e5d
+  - scheme: DOI
+    text: 10.1000/182
+title: My Book
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := pub.UniqueIdentifier(); id != "pub-id-02" {
+		t.Errorf("bad default unique identifier: %v", id)
+	}
+
+	pub.Identifier[2].Primary = true
+	pkg := pub.Package()
+	if pkg.UniqueIdentifier != "pub-id-03" || pkg.Metadata.Identifier[2].ID != pkg.UniqueIdentifier {
+		t.Errorf("bad package unique identifier: %v", pkg.UniqueIdentifier)
+	}
+	if warnings := pub.Validate(); len(warnings) > 0 {
+		t.Error(warnings)
+	}
+
+	out, err := yaml.Marshal(pub.Identifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids Identifiers
+	if err := yaml.Unmarshal(out, &ids); err != nil {
+		t.Fatal(err)
+	}
+	if ids.Primary() != 2 {
+		t.Errorf("primary flag lost:\n%s", out)
+	}
+
+	// exporters use the primary identifier
+	const doi, urn = "10.1000/182", "urn:doi:10.1000/182"
+	if id := pub.Readium().Identifier; id != doi {
+		t.Errorf("bad Readium identifier: %v", id)
+	}
+	if id := pub.OPDSPublication().Metadata.Identifier; id != urn {
+		t.Errorf("bad OPDS 2 identifier: %v", id)
+	}
+	if id := pub.OPDSEntry().ID; id != urn {
+		t.Errorf("bad OPDS entry id: %v", id)
+	}
+	if id := pub.MARC21().Control("001"); id != doi {
+		t.Errorf("bad MARC control number: %v", id)
+	}
+	if id := pub.Audiobook().ID; id != urn {
+		t.Errorf("bad audiobook id: %v", id)
+	}
+	if data := pub.XMP(nil); !bytes.Contains(data, []byte("<dc:identifier>"+urn+"</dc:identifier>")) {
+		t.Errorf("bad XMP identifier:\n%s", data)
+	}
+	if data, err := pub.OOXMLCore(); err != nil ||
+		!bytes.Contains(data, []byte("<dc:identifier>"+doi+"</dc:identifier>")) {
+		t.Errorf("bad core properties identifier: %v\n%s", err, data)
+	}
+
+	pub.Identifier[0].Primary = true
+	if warnings := pub.Validate(); len(warnings) != 1 {
+		t.Errorf("expected primary warning: %v", warnings)
+	}
+}
+
+func TestEPUBOptions(t *testing.T) {
+	pub, err := Parse([]byte(`
+identifier:
+  - 978-3-16-148410-0
+  - urn:uuid:886313e1-3b8a-5372-9b90-0c9aee199e5d
+title:
+  - type: main
+    text: My Book
+creator:
+  - text: Jane Doe
+    file-as: Doe, Jane
+description: |
+  First line.
+  Second line.
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	def, err := xml.Marshal(pub.EPUB())
+	if err != nil {
+		t.Fatal(err)
+	}
+	zero, err := xml.Marshal(pub.EPUBWithOptions(EPUBOptions{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(def) != string(zero) {
+		t.Error("zero options differ from default conversion")
+	}
+
+	var processed bool
+	meta := pub.EPUBWithOptions(EPUBOptions{
+		IDPrefix:          "x-",
+		IDFormat:          "%d",
+		KeepDescription:   true,
+		SkipMainTitleType: true,
+		SkipFileAs:        true,
+		IdentifierType:    "scheme",
+		PostProcess:       func(*epub.Metadata) { processed = true },
+	})
+	if !processed {
+		t.Error("post process is not called")
+	}
+	if meta.Identifier[1].ID != "x-id2" {
+		t.Errorf("bad identifier ID: %v", meta.Identifier[1].ID)
+	}
+	if meta.Title[0].ID != "" {
+		t.Errorf("unexpected title ID: %v", meta.Title[0].ID)
+	}
+	if meta.Description[0].Value != "<p>First line.\nSecond line.</p>" {
+		t.Errorf("description is not sanitized HTML: %q", meta.Description[0].Value)
+	}
+	for _, m := range meta.Meta {
+		switch m.Property {
+		case "file-as", "title-type":
+			t.Errorf("unexpected refinement: %v", m)
+		case "identifier-type":
+			if m.Scheme != "" || (m.Value != "ISBN-13" && m.Value != "UUID") {
+				t.Errorf("bad identifier type: %v", m)
+			}
+		}
+	}
+
+	pkg := pub.PackageWithOptions(EPUBOptions{IDPrefix: "x-", IdentifierType: "none"})
+	if pkg.UniqueIdentifier != "x-id-02" {
+		t.Errorf("bad unique identifier: %v", pkg.UniqueIdentifier)
+	}
+	for _, m := range pkg.Metadata.Meta {
+		if m.Property == "identifier-type" {
+			t.Errorf("unexpected identifier type: %v", m)
+		}
+	}
+}
+
+func TestDescription(t *testing.T) {
+	var pub = Publication{
+		Description: "# About\n\nFirst *paragraph* with **bold** text,\n" +
+			"[link](https://example.com) and [bad](javascript:void).\n\n" +
+			"- one\n- two & three\n\n> Quote  \n> next line\n\nsnake_case <tag>",
+	}
+	const html = "<p><strong>About</strong></p>\n" +
+		"<p>First <em>paragraph</em> with <strong>bold</strong> text,\n" +
+		`<a href="https://example.com">link</a> and bad.</p>` + "\n" +
+		"<ul><li>one</li><li>two &amp; three</li></ul>\n" +
+		"<blockquote><p>Quote<br/>\nnext line</p></blockquote>\n" +
+		"<p>snake_case &lt;tag&gt;</p>"
+	if got := pub.DescriptionHTML(); got != html {
+		t.Errorf("bad HTML:\n%s", got)
+	}
+	const text = "About\n\nFirst paragraph with bold text, link and bad.\n\n" +
+		"- one\n- two & three\n\nQuote\nnext line\n\nsnake_case <tag>"
+	if got := pub.DescriptionText(); got != text {
+		t.Errorf("bad text:\n%s", got)
+	}
+	if got := pub.Teaser(20); got != "About First…" {
+		t.Errorf("bad teaser: %q", got)
+	}
+	pub.ShortDescription = "Short _one_."
+	if got := pub.Teaser(0); got != "Short one." {
+		t.Errorf("bad short description teaser: %q", got)
+	}
+
+	pub.Description = `<h2>Title</h2><div>Text <b onclick="x()">bold</b>` +
+		`<script>alert(1)</script><a href="javascript:x()">link</a><p>Next &amp; last<br>line`
+	if got := pub.DescriptionHTML(); got !=
+		`<p>Title</p>Text <b>bold</b><a>link</a><p>Next &amp; last<br/>line</p>` {
+		t.Errorf("bad sanitized HTML:\n%s", got)
+	}
+	if got := pub.DescriptionText(); got != "Title\n\nText boldlink\n\nNext & last\nline" {
+		t.Errorf("bad HTML text:\n%q", got)
+	}
+}
+
+func TestRights(t *testing.T) {
+	for text, want := range map[string]Rights{
+		"© 2007 John Smith, CC BY-NC":                            {Holder: "John Smith", Year: 2007, License: "CC-BY-NC-4.0"},
+		"Copyright (c) 1999-2004 My Press. All rights reserved.": {Holder: "My Press", Year: 2004},
+		"(C) 2020 Nokia, licensed under MIT":                     {Holder: "Nokia", Year: 2020, License: "MIT"},
+		"© 2021 Jane Doe. SPDX: Apache-2.0":                      {Holder: "Jane Doe", Year: 2021, License: "Apache-2.0"},
+		"© 2020 MIT Press":                                       {Holder: "MIT Press", Year: 2020},
+		"Copyright 2019 W3C":                                     {Holder: "W3C", Year: 2019},
+		"© 2018 Zlib Books, GPL edition":                         {Holder: "Zlib Books", Year: 2018},
+		"https://creativecommons.org/licenses/by-sa/3.0/de/":     {License: "CC-BY-SA-3.0-DE", URL: "https://creativecommons.org/licenses/by-sa/3.0/de/"},
+		"Public domain, CC0":                                     {License: "CC0-1.0"},
+	} {
+		if got := ParseRights(text); got != want {
+			t.Errorf("%q: %+v", text, got)
+		}
+	}
+
+	var pub = Publication{
+		Title:           Titles{{Text: "My Book"}},
+		CopyrightHolder: "Jane Doe",
+		CopyrightYear:   2021,
+		License:         "cc-by-4.0",
+	}
+	if text := pub.RightsText(); text != "© 2021 Jane Doe, CC BY 4.0" {
+		t.Errorf("bad rights text: %q", text)
+	}
+	meta := pub.EPUB()
+	if len(meta.Rights) != 1 || len(meta.Link) != 2 ||
+		meta.Link[0].Rel != "dcterms:license" || meta.Link[1].Rel != "cc:license" ||
+		meta.Link[0].Href != "https://creativecommons.org/licenses/by/4.0/" {
+		t.Errorf("bad EPUB rights: %+v %+v", meta.Rights, meta.Link)
+	}
+	if manifest := pub.Audiobook(); manifest.License != meta.Link[0].Href ||
+		manifest.CopyrightYear != 2021 || manifest.CopyrightHolder.Name != "Jane Doe" {
+		t.Errorf("bad audiobook rights: %+v", manifest)
+	}
+	if opds2 := pub.OPDSPublication(); opds2.Metadata.License != meta.Link[0].Href {
+		t.Errorf("bad OPDS 2 license: %q", opds2.Metadata.License)
+	}
+	if data, err := json.Marshal(pub.Readium()); err != nil {
+		t.Fatal(err)
+	} else if got, err := ParseReadium([]byte(`{"metadata":` + string(data) + `}`)); err != nil ||
+		got.LicenseURL != meta.Link[0].Href {
+		t.Errorf("bad Readium license: %v\n%s", err, data)
+	}
+
+	// licenses parsed from the rights text are not linked
+	var parsed = Publication{Title: Titles{{Text: "My Book"}}, Rights: "© 2020 Jane Doe, CC BY 4.0"}
+	if meta := parsed.EPUB(); len(meta.Link) != 0 || len(meta.Rights) != 1 {
+		t.Errorf("parsed license is linked: %+v", meta.Link)
+	}
+	if manifest := parsed.Audiobook(); manifest.License != "" {
+		t.Errorf("parsed audiobook license: %q", manifest.License)
+	}
+	if license := parsed.Readium().License; license != "" {
+		t.Errorf("parsed Readium license: %q", license)
+	}
+	if fields := parsed.MARC21().Field("540"); len(fields) != 1 || fields[0].Subfield("u") != "" {
+		t.Errorf("parsed MARC license URL: %v", fields)
+	}
+
+	pub.License = "Apache 2"
+	if warnings := validateRights(pub); len(warnings) != 1 {
+		t.Errorf("expected license warning: %v", warnings)
+	}
+}
+
+func TestExtensions(t *testing.T) {
+	pub, err := Parse([]byte(`
+title: My Book
+schema:accessMode: [textual, visual]
+schema:accessibilitySummary: Short summary.
+a11y:certifierReport: https://example.com/report.html
+ibooks:binding: false
+custom: value
+marc:500: [note]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pub.Extensions) != 3 || len(pub.Properties) != 3 {
+		t.Fatalf("bad extensions: %v %v", pub.Extensions, pub.Properties)
+	}
+	if modes, ok := pub.Extensions["schema:accessMode"].(*MetaValues); !ok || len(*modes) != 2 {
+		t.Errorf("bad access modes: %#v", pub.Extensions["schema:accessMode"])
+	}
+
+	pkg := pub.Package()
+	if got := metaProperties(pkg.Metadata); got != "schema:accessMode=textual "+
+		"schema:accessMode=visual schema:accessibilitySummary=Short summary. ibooks:binding=false" {
+		t.Errorf("bad metas: %v", got)
+	}
+	if len(pkg.Metadata.Link) != 1 || pkg.Metadata.Link[0].Rel != "a11y:certifierReport" {
+		t.Errorf("bad links: %v", pkg.Metadata.Link)
+	}
+	if pkg.Prefix != "ibooks: http://vocabulary.itunes.apple.com/rdf/ibooks/vocabulary-extensions-1.0/" {
+		t.Errorf("bad prefix: %v", pkg.Prefix)
+	}
+
+	out, err := yaml.Marshal(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := Parse(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Extensions) != 3 {
+		t.Errorf("extensions lost:\n%s", out)
+	}
+
+	if _, err := Parse([]byte("schema:accessMode: {a: b}")); err == nil {
+		t.Error("expected extension decode error")
+	}
+}
+
+// metaProperties return not refining EPUB metas as "property=value" list.
+func metaProperties(meta epub.Metadata) string {
+	var properties []string
+	for _, m := range meta.Meta {
+		if m.Refines == "" {
+			properties = append(properties, m.Property+"="+m.Value)
+		}
+	}
+	return strings.Join(properties, " ")
+}
+
+func TestEPUBPrefix(t *testing.T) {
+	var meta = epub.Metadata{
+		Meta: []epub.Meta{
+			{Property: "ibooks:version", Value: "1.0"},
+			{Property: "role", Scheme: "marc:relators", Value: "aut"},
+			{Property: "dcterms:modified", Value: "2021-01-01T00:00:00Z"},
+			{Property: "my:custom", Value: "value"},
+		},
+		Link: []epub.Link{{Rel: "cc:license", Href: "https://creativecommons.org/licenses/by/4.0/"}},
+	}
+	prefix, err := EPUBPrefix(meta)
+	if err == nil || prefix != "cc: http://creativecommons.org/ns# "+
+		"ibooks: http://vocabulary.itunes.apple.com/rdf/ibooks/vocabulary-extensions-1.0/" {
+		t.Errorf("bad prefix: %q %v", prefix, err)
+	}
+
+	RegisterPrefix("my", "https://example.com/vocab#")
+	defer delete(vocabularies, "my")
+	RegisterPrefix("dcterms", "https://example.com/dcterms#")
+	if prefix, err := EPUBPrefix(meta); err != nil || !strings.HasSuffix(prefix, " my: https://example.com/vocab#") {
+		t.Errorf("bad prefix with registered one: %q %v", prefix, err)
+	}
+}
+
+func TestVendors(t *testing.T) {
+	pub, err := Parse([]byte(`
+title: My Comic
+ibooks:
+  version: 1.2.3
+  specified-fonts: true
+  ipad-orientation-lock: landscape-only
+  binding: false
+kobo:
+  fixed-layout: true
+  spread: none
+kindle:
+  fixed-layout: true
+  primary-writing-mode: horizontal-rl
+  original-resolution: 1024x600
+  book-type: comic
+  region-magnification: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warnings := pub.Validate(); len(warnings) > 0 {
+		t.Error(warnings)
+	}
+
+	// Apple Books expects "true", not "yes" written by the earlier versions
+	if got := metaProperties(pub.EPUB()); got != "ibooks:version=1.2.3 ibooks:specified-fonts=true "+
+		"ibooks:ipad-orientation-lock=landscape-only ibooks:binding=false "+
+		"rendition:layout=pre-paginated rendition:spread=none" {
+		t.Errorf("bad metas: %v", got)
+	}
+
+	var names []string
+	for _, m := range pub.EPUB2().Meta {
+		names = append(names, m.Name+"="+m.Content)
+	}
+	if got := strings.Join(names, " "); got != "primary-writing-mode=horizontal-rl fixed-layout=true "+
+		"original-resolution=1024x600 book-type=comic RegionMagnification=true" {
+		t.Errorf("bad Kindle metas: %v", got)
+	}
+
+	pub.IBooks.ScrollAxis = "diagonal"
+	pub.Kindle.OriginalResolution = "1024"
+	if warnings := pub.Validate(); len(warnings) != 2 {
+		t.Errorf("expected vendor warnings: %v", warnings)
+	}
+}
+
+func TestCoverImage(t *testing.T) {
+	var dir = t.TempDir()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 60, 90))); err != nil {
+		t.Fatal(err)
+	}
+	var webp = []byte("RIFF\x00\x00\x00\x00WEBPVP8X\x0a\x00\x00\x00\x00\x00\x00\x00\x1d\x00\x00\x2c\x00\x00")
+	for name, data := range map[string][]byte{
+		"cover.png":  buf.Bytes(),
+		"thumb.webp": webp,
+		"small.svg":  []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 20 30"/>`),
+		"meta.yaml": []byte("title: My Book\ncover-image:\n  path: cover.png\n  alt: Book cover\n" +
+			"  thumbnails: [thumb.webp, small.svg]\n"),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pub, err := Load(filepath.Join(dir, "meta.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cover := pub.CoverImage
+	if cover.MediaType != "image/png" || cover.Width != 60 || cover.Height != 90 ||
+		cover.Thumbnails[0].MediaType != "image/webp" || cover.Thumbnails[0].Width != 30 ||
+		cover.Thumbnails[0].Height != 45 || cover.Thumbnails[1].MediaType != "image/svg+xml" ||
+		cover.Thumbnails[1].Width != 20 || cover.Thumbnails[1].Height != 30 {
+		t.Errorf("bad cover image: %+v", cover)
+	}
+	if warnings := pub.Validate(); len(warnings) > 0 {
+		t.Error(warnings)
+	}
+
+	items := pub.Package().Manifest.Items
+	if len(items) != 3 || items[0].ID != CoverImageID || items[0].Properties != "cover-image" ||
+		items[0].MediaType != "image/png" || items[2].Href != "small.svg" {
+		t.Errorf("bad manifest: %+v", items)
+	}
+	if meta := pub.EPUB2().Meta; len(meta) != 1 || meta[0].Name != "cover" || meta[0].Content != CoverImageID {
+		t.Errorf("bad EPUB 2 cover meta: %+v", meta)
+	}
+
+	pub.CoverImage.Thumbnails[0].Path = "missing.jpg"
+	pub.CoverImage.MediaType = "image/jpeg"
+	if warnings := pub.Validate(); len(warnings) != 2 {
+		t.Errorf("expected cover warnings: %v", warnings)
+	}
+
+	// not loaded publication: files are checked by ValidateFiles only
+	parsed := Publication{CoverImage: &CoverImage{Path: "missing.png", Alt: "Cover",
+		Thumbnails: []CoverImage{{Path: "small.svg"}}}}
+	if warnings := parsed.Validate(); len(warnings) > 0 {
+		t.Errorf("unexpected file warnings: %v", warnings)
+	}
+	if warnings := parsed.ValidateFiles(dir); len(warnings) != 1 {
+		t.Errorf("expected missing file warning: %v", warnings)
+	}
+	if err := parsed.CoverImage.Sniff(dir); err == nil ||
+		parsed.CoverImage.Thumbnails[0].MediaType != "image/svg+xml" {
+		t.Errorf("thumbnails are not sniffed: %v %+v", err, parsed.CoverImage.Thumbnails)
+	}
+
+	out, err := yaml.Marshal(&Publication{CoverImage: &CoverImage{Path: "cover.jpg"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "cover-image: cover.jpg\n") {
+		t.Errorf("bad short cover image form:\n%s", out)
+	}
+}
+
+func TestReadISO2709(t *testing.T) {
+	data, err := Publication{Title: Titles{{Type: "main", Text: "My Book"}}}.MARC21().ISO2709()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// records separated by line breaks
+	var stream = append(append(append([]byte("\r\n"), data...), '\n'), data...)
+	records, err := ReadISO2709(bytes.NewReader(stream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || len(records[1].Field("245")) != 1 {
+		t.Errorf("bad records: %v", records)
+	}
+
+	for name, malformed := range map[string]string{
+		"short":     "00010nam",
+		"length":    "-0030nam a2200000 i 4500\x1e\x1d",
+		"base":      "00026nam a22+0025 i 4500\x1e\x1d",
+		"entry":     "00051nam a2200037 i 4500245-0005-9999\x1e10\x1faA\x1e\x1d",
+		"signed":    "00051nam a2200037 i 45002450005-9999\x1e10\x1faA\x1e\x1d",
+		"overflow":  "00051nam a2200037 i 4500245000500099\x1e10\x1faA\x1e\x1d",
+		"directory": "00040nam a2200030 i 450024500\x1e10\x1faA\x1e\x1d",
+	} {
+		if _, err := ReadISO2709(strings.NewReader(malformed)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestReadiumSubjects(t *testing.T) {
+	got, err := ParseReadium([]byte(`{"metadata": {"title": "My Book",
+		"subject": ["Fiction", {"name": "Science Fiction", "code": "FIC028000", "scheme": "https://www.bisg.org/#bisac"}]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Subject) != 2 || got.Subject[1] != "Science Fiction" {
+		t.Errorf("bad subjects: %v", got.Subject)
+	}
+	var meta ReadiumMetadata
+	if err := json.Unmarshal([]byte(`{"subject": {"name": "Fiction", "code": "FIC000000"}}`), &meta); err != nil {
+		t.Fatal(err)
+	}
+	if len(meta.Subject) != 1 || meta.Subject[0].Code != "FIC000000" {
+		t.Errorf("bad subject: %v", meta.Subject)
+	}
+	data, err := json.Marshal(meta.Subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"name":"Fiction","code":"FIC000000"}` {
+		t.Errorf("bad subject JSON: %s", data)
+	}
+}
+
+func TestEPUBModified(t *testing.T) {
+	for date, want := range map[Date]string{
+		"2021-05-01":                "2021-05-01T00:00:00Z",
+		"2021-05-01T12:30:00+03:00": "2021-05-01T09:30:00Z",
+		"2021-05":                   "",
+	} {
+		pub := Publication{Title: Titles{{Text: "My Book"}}, Modified: date}
+		var got string
+		for _, meta := range pub.EPUB().Meta {
+			if meta.Property == "dcterms:modified" {
+				got = meta.Value
+			}
+		}
+		if got != want {
+			t.Errorf("%s: bad modified %q, want %q", date, got, want)
+		}
+		if warnings := validateModified(date); (len(warnings) > 0) != (want == "") {
+			t.Errorf("%s: bad warnings %v", date, warnings)
+		}
+	}
+}
+
+func TestFB2Charsets(t *testing.T) {
+	for name, table := range charsets {
+		if len(table) != 128 {
+			t.Fatalf("%s: bad charset table size %d", name, len(table))
+		}
+		var encode = make(map[rune]byte)
+		for i, r := range table {
+			encode[r] = byte(0x80 + i)
+		}
+		var data []byte
+		for _, r := range `<?xml version="1.0" encoding="` + name + `"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0">
+<description><title-info>
+  <book-title>Книга</book-title>
+  <annotation><p>Первый <emphasis>абзац</emphasis> и <strong>ссылка</strong>.</p></annotation>
+  <lang>ru</lang>
+</title-info></description>
+</FictionBook>` {
+			if r < 0x80 {
+				data = append(data, byte(r))
+			} else {
+				data = append(data, encode[r])
+			}
+		}
+		pub, err := ParseFB2(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if pub.Title.Main() != "Книга" || pub.Description != "Первый абзац и ссылка." {
+			t.Errorf("%s: bad publication: %v %q", name, pub.Title, pub.Description)
+		}
+	}
+	if _, err := ParseFB2([]byte(`<?xml version="1.0" encoding="iso-8859-5"?><FictionBook/>`)); err == nil {
+		t.Error("expected unsupported charset error")
+	}
+
+	pub := Publication{Title: Titles{{Type: "main", Text: "Book"}}, Date: "21", Publisher: "Press"}
+	if d := pub.FB2(); d.TitleInfo.Date != nil || d.PublishInfo.Year != "" {
+		t.Errorf("bad short date: %+v %+v", d.TitleInfo.Date, d.PublishInfo)
+	}
+}
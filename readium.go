@@ -0,0 +1,551 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ReadiumMetadata is a metadata section of Readium Web Publication Manifest.
+//
+// https://readium.org/webpub-manifest/
+type ReadiumMetadata struct {
+	Type               string                         `json:"@type,omitempty"`
+	Identifier         string                         `json:"identifier,omitempty"`
+	Title              ReadiumString                  `json:"title"`
+	Subtitle           ReadiumString                  `json:"subtitle,omitempty"`
+	SortAs             ReadiumString                  `json:"sortAs,omitempty"`
+	Contributors       map[string]ReadiumContributors `json:"-"` // by role
+	Language           ReadiumLanguages               `json:"language,omitempty"`
+	Published          string                         `json:"published,omitempty"`
+	Modified           string                         `json:"modified,omitempty"`
+	Description        string                         `json:"description,omitempty"`
+	License            string                         `json:"license,omitempty"`
+	BelongsTo          map[string]ReadiumContributors `json:"belongsTo,omitempty"`
+	Subject            ReadiumSubjects                `json:"subject,omitempty"`
+	ReadingProgression string                         `json:"readingProgression,omitempty"`
+	Presentation       map[string]interface{}         `json:"presentation,omitempty"`
+}
+
+// ReadiumRoles lists the contributor roles of Readium Web Publication
+// Manifest with the corresponding MARC relator codes. The publisher role is
+// defined by the publication publisher only.
+var ReadiumRoles = map[string]string{
+	"aut": "author",
+	"trl": "translator",
+	"edt": "editor",
+	"art": "artist",
+	"ill": "illustrator",
+	"clr": "colorist",
+	"nrt": "narrator",
+}
+
+// ReadiumString is a localized string: a map of language to value. It is
+// encoded as a plain string when only one value is defined.
+type ReadiumString map[string]string
+
+// MarshalJSON implement json.Marshaler interface.
+func (s ReadiumString) MarshalJSON() ([]byte, error) {
+	if len(s) == 1 {
+		for _, value := range s {
+			return json.Marshal(value)
+		}
+	}
+	return json.Marshal(map[string]string(s))
+}
+
+// UnmarshalJSON implement json.Unmarshaler interface.
+func (s *ReadiumString) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*s = ReadiumString{"": text}
+		return nil
+	}
+	return json.Unmarshal(data, (*map[string]string)(s))
+}
+
+// languages return sorted list of languages with empty first.
+func (s ReadiumString) languages() []string {
+	var list = make([]string, 0, len(s))
+	for lang := range s {
+		list = append(list, lang)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// ReadiumLanguages is a list of languages encoded as a string if only one.
+type ReadiumLanguages []string
+
+// MarshalJSON implement json.Marshaler interface.
+func (l ReadiumLanguages) MarshalJSON() ([]byte, error) {
+	if len(l) == 1 {
+		return json.Marshal(l[0])
+	}
+	return json.Marshal([]string(l))
+}
+
+// UnmarshalJSON implement json.Unmarshaler interface.
+func (l *ReadiumLanguages) UnmarshalJSON(data []byte) error {
+	var lang string
+	if err := json.Unmarshal(data, &lang); err == nil {
+		*l = ReadiumLanguages{lang}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+// ReadiumContributor describe a contributor, a series or a collection.
+type ReadiumContributor struct {
+	Name     ReadiumString `json:"name"`
+	SortAs   string        `json:"sortAs,omitempty"`
+	Role     []string      `json:"role,omitempty"`
+	Position *float64      `json:"position,omitempty"`
+}
+
+type readiumContributor ReadiumContributor // alias
+
+// MarshalJSON implement json.Marshaler interface.
+func (c ReadiumContributor) MarshalJSON() ([]byte, error) {
+	if c.SortAs == "" && len(c.Role) == 0 && c.Position == nil {
+		return json.Marshal(c.Name)
+	}
+	return json.Marshal(readiumContributor(c))
+}
+
+// UnmarshalJSON implement json.Unmarshaler interface.
+func (c *ReadiumContributor) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*c = ReadiumContributor{Name: ReadiumString{"": name}}
+		return nil
+	}
+	return json.Unmarshal(data, (*readiumContributor)(c))
+}
+
+// ReadiumContributors is a list of contributors.
+type ReadiumContributors []ReadiumContributor
+
+// MarshalJSON implement json.Marshaler interface.
+func (list ReadiumContributors) MarshalJSON() ([]byte, error) {
+	if len(list) == 1 {
+		return json.Marshal(list[0])
+	}
+	return json.Marshal([]ReadiumContributor(list))
+}
+
+// UnmarshalJSON implement json.Unmarshaler interface.
+func (list *ReadiumContributors) UnmarshalJSON(data []byte) error {
+	if data = []byte(strings.TrimSpace(string(data))); len(data) > 0 && data[0] == '[' {
+		return json.Unmarshal(data, (*[]ReadiumContributor)(list))
+	}
+	var c ReadiumContributor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return err
+	}
+	*list = ReadiumContributors{c}
+	return nil
+}
+
+// ReadiumSubject describe a subject. It is encoded as a plain string when
+// only the name is defined.
+type ReadiumSubject struct {
+	Name   ReadiumString `json:"name"`
+	SortAs string        `json:"sortAs,omitempty"`
+	Code   string        `json:"code,omitempty"`
+	Scheme string        `json:"scheme,omitempty"`
+}
+
+type readiumSubject ReadiumSubject // alias
+
+// MarshalJSON implement json.Marshaler interface.
+func (s ReadiumSubject) MarshalJSON() ([]byte, error) {
+	if s.SortAs == "" && s.Code == "" && s.Scheme == "" {
+		return json.Marshal(s.Name)
+	}
+	return json.Marshal(readiumSubject(s))
+}
+
+// UnmarshalJSON implement json.Unmarshaler interface.
+func (s *ReadiumSubject) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*s = ReadiumSubject{Name: ReadiumString{"": name}}
+		return nil
+	}
+	return json.Unmarshal(data, (*readiumSubject)(s))
+}
+
+// ReadiumSubjects is a list of subjects.
+type ReadiumSubjects []ReadiumSubject
+
+// MarshalJSON implement json.Marshaler interface.
+func (list ReadiumSubjects) MarshalJSON() ([]byte, error) {
+	if len(list) == 1 {
+		return json.Marshal(list[0])
+	}
+	return json.Marshal([]ReadiumSubject(list))
+}
+
+// UnmarshalJSON implement json.Unmarshaler interface.
+func (list *ReadiumSubjects) UnmarshalJSON(data []byte) error {
+	if data = []byte(strings.TrimSpace(string(data))); len(data) > 0 && data[0] == '[' {
+		return json.Unmarshal(data, (*[]ReadiumSubject)(list))
+	}
+	var s ReadiumSubject
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*list = ReadiumSubjects{s}
+	return nil
+}
+
+type readiumMetadata ReadiumMetadata // alias
+
+// MarshalJSON implement json.Marshaler interface.
+func (m ReadiumMetadata) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(readiumMetadata(m))
+	if err != nil || len(m.Contributors) == 0 {
+		return data, err
+	}
+
+	var obj = make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	for role, list := range m.Contributors {
+		if obj[role], err = json.Marshal(list); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(obj)
+}
+
+// UnmarshalJSON implement json.Unmarshaler interface.
+func (m *ReadiumMetadata) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*readiumMetadata)(m)); err != nil {
+		return err
+	}
+
+	var obj = make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	for _, role := range append(readiumRoleNames(), "publisher", "contributor") {
+		raw, ok := obj[role]
+		if !ok {
+			continue
+		}
+		var list ReadiumContributors
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return fmt.Errorf("bad readium %s: %w", role, err)
+		}
+		if m.Contributors == nil {
+			m.Contributors = make(map[string]ReadiumContributors)
+		}
+		m.Contributors[role] = list
+	}
+	return nil
+}
+
+// readiumRoleNames return sorted list of Readium contributor roles.
+func readiumRoleNames() []string {
+	var list = make([]string, 0, len(ReadiumRoles))
+	for _, role := range ReadiumRoles {
+		list = append(list, role)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Readium return publication metadata as Readium Web Publication Manifest
+// metadata.
+func (p Publication) Readium() ReadiumMetadata {
+	var meta = ReadiumMetadata{
+		Type:        "http://schema.org/Book",
+		Published:   string(p.Date),
+		Modified:    string(p.Modified),
+		Description: p.DescriptionText(),
+		License:     p.license().LicenseURL(),
+	}
+
+	if id, ok := p.Identifier.primary(); ok {
+		meta.Identifier = id.Text
+	}
+
+	// localized titles
+	for _, title := range p.Title {
+		var lang = title.Lang
+		if lang == "" {
+			lang = p.Language
+		}
+		var target *ReadiumString
+		switch title.Type {
+		case "", "main":
+			target = &meta.Title
+		case "subtitle":
+			target = &meta.Subtitle
+		default:
+			continue
+		}
+		if *target == nil {
+			*target = make(ReadiumString)
+		}
+		if _, ok := (*target)[lang]; !ok {
+			(*target)[lang] = title.Text
+		}
+		if title.FileAs != "" && title.Type != "subtitle" {
+			if meta.SortAs == nil {
+				meta.SortAs = make(ReadiumString)
+			}
+			if _, ok := meta.SortAs[lang]; !ok {
+				meta.SortAs[lang] = title.FileAs
+			}
+		}
+	}
+
+	// contributors by role
+	addContributor := func(author Author, defaultRole string) {
+		var c = ReadiumContributor{
+			Name:   ReadiumString{"": author.Text},
+			SortAs: author.FileAs,
+		}
+		var roles = make(map[string]bool)
+		var other []string
+		for _, code := range author.Relators(p.Language) {
+			if role, ok := ReadiumRoles[code]; ok {
+				roles[role] = true
+			} else {
+				other = append(other, code)
+			}
+		}
+		if len(roles) == 0 && len(other) == 0 {
+			roles[defaultRole] = true
+		}
+		if len(other) > 0 {
+			c.Role = other
+			roles["contributor"] = true
+		}
+		if meta.Contributors == nil {
+			meta.Contributors = make(map[string]ReadiumContributors)
+		}
+		for role := range roles {
+			var rc = c
+			if role != "contributor" {
+				rc.Role = nil
+			}
+			meta.Contributors[role] = append(meta.Contributors[role], rc)
+		}
+	}
+	for _, author := range p.Creator.Sorted() {
+		addContributor(author, "author")
+	}
+	for _, author := range p.Contributor.Sorted() {
+		addContributor(author, "contributor")
+	}
+	if p.Publisher != "" {
+		if meta.Contributors == nil {
+			meta.Contributors = make(map[string]ReadiumContributors)
+		}
+		meta.Contributors["publisher"] = append(meta.Contributors["publisher"],
+			ReadiumContributor{Name: ReadiumString{"": p.Publisher}})
+	}
+
+	if p.Language != "" {
+		meta.Language = ReadiumLanguages{p.Language}
+	}
+
+	// collection
+	if p.BelongsToCollection != "" {
+		var c = ReadiumContributor{Name: ReadiumString{"": p.BelongsToCollection}}
+		var kind = "collection"
+		if p.GroupPosition != "" {
+			kind = "series"
+			if position, err := strconv.ParseFloat(p.GroupPosition, 64); err == nil {
+				c.Position = &position
+			}
+		}
+		meta.BelongsTo = map[string]ReadiumContributors{kind: {c}}
+	}
+
+	for _, subject := range p.Subject {
+		meta.Subject = append(meta.Subject, ReadiumSubject{Name: ReadiumString{"": subject}})
+	}
+
+	switch p.PageDirection {
+	case "ltr", "rtl":
+		meta.ReadingProgression = p.PageDirection
+	case "default":
+		meta.ReadingProgression = "auto"
+	}
+
+	switch p.Layout {
+	case "pre-paginated":
+		meta.Presentation = map[string]interface{}{"layout": "fixed"}
+	case "reflowable":
+		meta.Presentation = map[string]interface{}{"layout": "reflowable"}
+	}
+
+	return meta
+}
+
+// ParseReadium return publication metadata from Readium Web Publication
+// Manifest. The data may be the full manifest or its metadata object only.
+func ParseReadium(data []byte) (*Publication, error) {
+	var manifest struct {
+		Metadata *ReadiumMetadata `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	var meta = manifest.Metadata
+	if meta == nil {
+		meta = new(ReadiumMetadata)
+		if err := json.Unmarshal(data, meta); err != nil {
+			return nil, err
+		}
+	}
+	return meta.Publication(), nil
+}
+
+// Publication return publication metadata from Readium metadata.
+func (m ReadiumMetadata) Publication() *Publication {
+	var pub = &Publication{
+		Date:        Date(m.Published),
+		Modified:    Date(m.Modified),
+		Description: m.Description,
+		LicenseURL:  m.License,
+	}
+	if m.Identifier != "" {
+		var id = Identifier{Text: m.Identifier}
+		id.detectScheme()
+		pub.Identifier = Identifiers{id}
+	}
+	if len(m.Language) > 0 {
+		pub.Language = m.Language[0]
+	}
+
+	// titles
+	addTitles := func(s ReadiumString, tt string) {
+		for _, lang := range s.languages() {
+			var title = Title{Type: tt, Text: s[lang]}
+			if lang != pub.Language {
+				title.Lang = lang
+			}
+			if tt == "main" {
+				sortAs, ok := m.SortAs[lang]
+				if !ok && (lang == "" || lang == pub.Language) {
+					sortAs = m.SortAs[""]
+				}
+				title.FileAs = sortAs
+			}
+			pub.Title = append(pub.Title, title)
+		}
+	}
+	addTitles(m.Title, "main")
+	addTitles(m.Subtitle, "subtitle")
+
+	// contributors
+	var codes = make(map[string]string, len(ReadiumRoles))
+	for code, role := range ReadiumRoles {
+		codes[role] = code
+	}
+	// authors first to merge other roles of creators
+	var roles = []string{"author"}
+	for _, role := range readiumRoleNames() {
+		if role != "author" {
+			roles = append(roles, role)
+		}
+	}
+	for _, role := range append(roles, "publisher", "contributor") {
+		for _, c := range m.Contributors[role] {
+			var name = c.Name.value(pub.Language)
+			if role == "publisher" {
+				if pub.Publisher == "" {
+					pub.Publisher = name
+				}
+				continue
+			}
+			var author = Author{Text: name, FileAs: c.SortAs, Role: c.Role}
+			if code, ok := codes[role]; ok {
+				author.Role = Strings{code}
+			}
+			if role == "author" || pub.Creator.has(author.Text) {
+				pub.Creator = pub.Creator.merge(author)
+			} else {
+				pub.Contributor = pub.Contributor.merge(author)
+			}
+		}
+	}
+
+	// collections
+	for _, kind := range []string{"series", "collection"} {
+		for _, c := range m.BelongsTo[kind] {
+			if pub.BelongsToCollection != "" {
+				break
+			}
+			pub.BelongsToCollection = c.Name.value(pub.Language)
+			if c.Position != nil {
+				pub.GroupPosition = strconv.FormatFloat(*c.Position, 'f', -1, 64)
+			}
+		}
+	}
+
+	for _, subject := range m.Subject {
+		pub.Subject = append(pub.Subject, subject.Name.value(pub.Language))
+	}
+
+	switch m.ReadingProgression {
+	case "ltr", "rtl":
+		pub.PageDirection = m.ReadingProgression
+	case "auto":
+		pub.PageDirection = "default"
+	}
+
+	switch m.Presentation["layout"] {
+	case "fixed":
+		pub.Layout = "pre-paginated"
+	case "reflowable":
+		pub.Layout = "reflowable"
+	}
+
+	return pub
+}
+
+// value return localized string for lang or the first one.
+func (s ReadiumString) value(lang string) string {
+	if value, ok := s[lang]; ok {
+		return value
+	}
+	if value, ok := s[""]; ok {
+		return value
+	}
+	if langs := s.languages(); len(langs) > 0 {
+		return s[langs[0]]
+	}
+	return ""
+}
+
+// has return true if the author with name is in the list.
+func (authors Authors) has(name string) bool {
+	for _, author := range authors {
+		if author.Text == name {
+			return true
+		}
+	}
+	return false
+}
+
+// merge add author to the list or append its roles if the author with the
+// same name already exists.
+func (authors Authors) merge(author Author) Authors {
+	for i := range authors {
+		if authors[i].Text == author.Text {
+			authors[i].Role = append(authors[i].Role, author.Role...)
+			return authors
+		}
+	}
+	return append(authors, author)
+}
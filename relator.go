@@ -0,0 +1,306 @@
+package metadata
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// MARCLabels is a reverse MARC Code List for Relators: code to english label.
+var MARCLabels = make(map[string]string, len(MARCCodes))
+
+func init() {
+	for label, code := range MARCCodes {
+		// keep the first label in alphabetical order for stable result
+		if prev, ok := MARCLabels[code]; !ok || label < prev {
+			MARCLabels[code] = label
+		}
+	}
+}
+
+// LocalizedMARCCodes contains localized relator labels by language code.
+// Each table maps the lower-cased label to MARC code.
+//
+// Users may add or extend tables for other languages.
+var LocalizedMARCCodes = map[string]map[string]string{
+	"ru": {
+		"автор":                      "aut",
+		"редактор":                   "edt",
+		"переводчик":                 "trl",
+		"иллюстратор":                "ill",
+		"художник":                   "art",
+		"художник обложки":           "cov",
+		"рассказчик":                 "nrt",
+		"чтец":                       "nrt",
+		"составитель":                "com",
+		"комментатор":                "cmm",
+		"автор предисловия":          "wpr",
+		"автор послесловия":          "aft",
+		"автор вступительной статьи": "aui",
+		"фотограф":                   "pht",
+		"дизайнер":                   "dsr",
+		"издатель":                   "pbl",
+		"корректор":                  "pfr",
+		"рецензент":                  "rev",
+		"композитор":                 "cmp",
+		"колорист":                   "clr",
+		"аннотатор":                  "ann",
+		"картограф":                  "ctg",
+		"интервьюер":                 "ivr",
+		"создатель":                  "cre",
+		"исполнитель":                "prf",
+		"участник":                   "ctb",
+	},
+	"de": {
+		"autor":                    "aut",
+		"autorin":                  "aut",
+		"herausgeber":              "edt",
+		"lektor":                   "edt",
+		"übersetzer":               "trl",
+		"übersetzerin":             "trl",
+		"illustrator":              "ill",
+		"illustratorin":            "ill",
+		"künstler":                 "art",
+		"erzähler":                 "nrt",
+		"sprecher":                 "nrt",
+		"fotograf":                 "pht",
+		"komponist":                "cmp",
+		"zusammensteller":          "com",
+		"verleger":                 "pbl",
+		"umschlaggestalter":        "cov",
+		"verfasser des vorworts":   "wpr",
+		"verfasser des nachworts":  "aft",
+		"verfasser der einleitung": "aui",
+		"bearbeiter":               "adp",
+		"korrektor":                "pfr",
+		"kolorist":                 "clr",
+		"mitwirkender":             "ctb",
+		"kommentator":              "cmm",
+		"gestalter":                "dsr",
+		"kartograf":                "ctg",
+	},
+	"fr": {
+		"auteur":                   "aut",
+		"éditeur scientifique":     "edt",
+		"directeur de publication": "edt",
+		"éditeur":                  "pbl",
+		"traducteur":               "trl",
+		"traductrice":              "trl",
+		"illustrateur":             "ill",
+		"illustratrice":            "ill",
+		"artiste":                  "art",
+		"narrateur":                "nrt",
+		"narratrice":               "nrt",
+		"photographe":              "pht",
+		"compositeur":              "cmp",
+		"compilateur":              "com",
+		"préfacier":                "wpr",
+		"postfacier":               "aft",
+		"auteur de l'introduction": "aui",
+		"adaptateur":               "adp",
+		"correcteur":               "pfr",
+		"coloriste":                "clr",
+		"collaborateur":            "ctb",
+		"commentateur":             "cmm",
+		"cartographe":              "ctg",
+	},
+	"es": {
+		"autor":                 "aut",
+		"autora":                "aut",
+		"editor":                "edt",
+		"editora":               "edt",
+		"traductor":             "trl",
+		"traductora":            "trl",
+		"ilustrador":            "ill",
+		"ilustradora":           "ill",
+		"artista":               "art",
+		"narrador":              "nrt",
+		"narradora":             "nrt",
+		"fotógrafo":             "pht",
+		"compositor":            "cmp",
+		"compilador":            "com",
+		"prologuista":           "wpr",
+		"autor del epílogo":     "aft",
+		"adaptador":             "adp",
+		"corrector":             "pfr",
+		"colorista":             "clr",
+		"colaborador":           "ctb",
+		"comentarista":          "cmm",
+		"editorial":             "pbl",
+		"cartógrafo":            "ctg",
+		"diseñador de cubierta": "cov",
+	},
+}
+
+// MARCToOnix is a crosswalk from MARC relator code to Onix CodeList 17:
+// Contributor role code.
+// https://onix-codelists.io/codelist/17
+var MARCToOnix = map[string]string{
+	"aut": "A01",
+	"aus": "A03",
+	"lbt": "A04",
+	"lyr": "A05",
+	"cmp": "A06",
+	"art": "A07",
+	"pht": "A08",
+	"cre": "A09",
+	"dsr": "A11",
+	"ill": "A12",
+	"wpr": "A15",
+	"aft": "A19",
+	"aui": "A24",
+	"win": "A24",
+	"ctb": "A32",
+	"cov": "A36",
+	"ctg": "A39",
+	"ivr": "A43",
+	"ive": "A44",
+	"edt": "B01",
+	"abr": "B04",
+	"adp": "B05",
+	"trl": "B06",
+	"com": "C01",
+	"pro": "D01",
+	"drt": "D02",
+	"prf": "E01",
+	"nrt": "E07",
+}
+
+// langTag return the primary language subtag in lower case.
+func langTag(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
+
+// LookupRelator return MARC relator code for role. The role may be a MARC
+// code, an english label or a label localized for lang. When lang is empty
+// or the role is not found in its table, all localized tables are checked.
+func LookupRelator(role, lang string) (code string, ok bool) {
+	role = strings.ToLower(strings.TrimSpace(role))
+	if role == "" {
+		return "", false
+	}
+	if _, ok := MARCLabels[role]; ok {
+		return role, true // already a code
+	}
+	if code, ok := MARCCodes[role]; ok {
+		return code, true
+	}
+	if table, ok := LocalizedMARCCodes[langTag(lang)]; ok {
+		if code, ok := table[role]; ok {
+			return code, true
+		}
+	}
+	// check all tables in stable order
+	var langs = make([]string, 0, len(LocalizedMARCCodes))
+	for lang := range LocalizedMARCCodes {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	for _, lang := range langs {
+		if code, ok := LocalizedMARCCodes[lang][role]; ok {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+// RelatorLabel return the label for MARC relator code localized for lang.
+// The english label is returned when no localized one is defined.
+func RelatorLabel(code, lang string) string {
+	code = strings.ToLower(code)
+	var label string
+	for l, c := range LocalizedMARCCodes[langTag(lang)] {
+		if c == code && (label == "" || l < label) {
+			label = l
+		}
+	}
+	if label != "" {
+		return label
+	}
+	return MARCLabels[code]
+}
+
+// OnixRole return Onix CodeList 17 contributor role for MARC relator code.
+// For unknown codes it returns "Z99" (Other).
+func OnixRole(code string) string {
+	if onix, ok := MARCToOnix[strings.ToLower(code)]; ok {
+		return onix
+	}
+	return "Z99"
+}
+
+// SuggestRelators return up to three known relator labels similar to the
+// role. It is used to suggest corrections for misspelled roles.
+func SuggestRelators(role, lang string) []string {
+	role = strings.ToLower(strings.TrimSpace(role))
+	var maxDist = 1
+	if utf8.RuneCountInString(role) > 5 {
+		maxDist = 2
+	}
+
+	type candidate struct {
+		label string
+		dist  int
+	}
+	var list []candidate
+	check := func(table map[string]string) {
+		for label := range table {
+			if d := levenshtein(role, label); d <= maxDist {
+				list = append(list, candidate{label, d})
+			}
+		}
+	}
+	check(MARCCodes)
+	check(LocalizedMARCCodes[langTag(lang)])
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].dist != list[j].dist {
+			return list[i].dist < list[j].dist
+		}
+		return list[i].label < list[j].label
+	})
+	if len(list) > 3 {
+		list = list[:3]
+	}
+	var result = make([]string, len(list))
+	for i, c := range list {
+		result[i] = c.label
+	}
+	return result
+}
+
+// levenshtein return edit distance between two strings.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
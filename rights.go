@@ -0,0 +1,269 @@
+package metadata
+
+import (
+	_ "embed" // SPDX license list
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed spdx.txt
+var spdxList string
+
+// spdxLicenses is a map of lower case SPDX license identifiers to canonical
+// form.
+var spdxLicenses = func() map[string]string {
+	var licenses = make(map[string]string)
+	for _, line := range strings.Split(spdxList, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			licenses[strings.ToLower(line)] = line
+		}
+	}
+	return licenses
+}()
+
+// LookupSPDX return the canonical SPDX license identifier or empty string
+// if the license is not in the SPDX license list.
+func LookupSPDX(id string) string {
+	return spdxLicenses[strings.ToLower(strings.TrimSpace(id))]
+}
+
+// Rights is a structured copyright and license statement.
+type Rights struct {
+	Holder  string // copyright holder
+	Year    int    // copyright year
+	License string // SPDX license identifier or license URL
+	URL     string // license URL
+}
+
+// Licensing return publication structured rights. Not defined fields are
+// parsed from the rights text.
+func (p Publication) Licensing() Rights {
+	var r = ParseRights(p.Rights)
+	if p.CopyrightHolder != "" {
+		r.Holder = p.CopyrightHolder
+	}
+	if p.CopyrightYear != 0 {
+		r.Year = p.CopyrightYear
+	}
+	if p.License != "" {
+		r.License = p.License
+	}
+	if p.LicenseURL != "" {
+		r.URL = p.LicenseURL
+	}
+	return r
+}
+
+// license return the license defined by the license fields. The license
+// parsed from the rights text is used in the rights text only and not in
+// license links and identifiers.
+func (p Publication) license() Rights {
+	return Rights{License: p.License, URL: p.LicenseURL}
+}
+
+// RightsText return the rights text or the text composed from the
+// structured rights.
+func (p Publication) RightsText() string {
+	if p.Rights != "" {
+		return p.Rights
+	}
+	return p.Licensing().String()
+}
+
+// SPDX return SPDX license identifier. Creative Commons license URLs are
+// converted to SPDX identifiers.
+func (r Rights) SPDX() string {
+	if id := LookupSPDX(r.License); id != "" {
+		return id
+	}
+	for _, link := range []string{r.License, r.URL} {
+		if id := ccSPDX(link); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// LicenseURL return the license URL: defined URL, the license if it is
+// URL, Creative Commons deed URL or SPDX license page URL.
+func (r Rights) LicenseURL() string {
+	if r.URL != "" {
+		return r.URL
+	}
+	if isURL(r.License) {
+		return r.License
+	}
+	var id = LookupSPDX(r.License)
+	if id == "" {
+		return ""
+	}
+	if link := spdxCC(id); link != "" {
+		return link
+	}
+	return "https://spdx.org/licenses/" + id + ".html"
+}
+
+// IsCC return true for Creative Commons licenses.
+func (r Rights) IsCC() bool {
+	return strings.HasPrefix(r.SPDX(), "CC") ||
+		strings.Contains(r.LicenseURL(), "creativecommons.org/")
+}
+
+// String return the rights text like "© 2007 John Smith, CC BY-NC 4.0".
+func (r Rights) String() string {
+	var parts []string
+	if r.Year != 0 || r.Holder != "" {
+		var copyright = "©"
+		if r.Year != 0 {
+			copyright += " " + strconv.Itoa(r.Year)
+		}
+		if r.Holder != "" {
+			copyright += " " + r.Holder
+		}
+		parts = append(parts, copyright)
+	}
+	if license := r.SPDX(); license != "" {
+		if match := reCCSPDX.FindStringSubmatch(license); match != nil {
+			// CC-BY-NC-4.0 -> CC BY-NC 4.0
+			license = strings.TrimSpace("CC " + match[1] + " " + match[2] + " " + match[3])
+		} else if strings.HasPrefix(license, "CC0-") {
+			license = "CC0 " + license[4:]
+		}
+		parts = append(parts, license)
+	} else if r.License != "" {
+		parts = append(parts, r.License)
+	}
+	return strings.Join(parts, ", ")
+}
+
+var (
+	reCCURL  = regexp.MustCompile(`(?i)^https?://(?:www\.)?creativecommons\.org/(licenses|publicdomain)/([a-z-]+)/(\d\.\d)(?:/([a-z]{2,3}))?/?`)
+	reCCSPDX = regexp.MustCompile(`^CC-(BY(?:-NC)?(?:-ND|-SA)?)-(\d\.\d)(?:-([A-Z]{2,3}))?$`)
+)
+
+// ccSPDX return SPDX identifier for Creative Commons license URL.
+func ccSPDX(link string) string {
+	var match = reCCURL.FindStringSubmatch(strings.TrimSpace(link))
+	if match == nil {
+		return ""
+	}
+	var id string
+	switch kind := strings.ToLower(match[2]); {
+	case strings.EqualFold(match[1], "publicdomain") && kind == "zero":
+		id = "CC0-" + match[3]
+	case strings.EqualFold(match[1], "licenses"):
+		id = "CC-" + strings.ToUpper(kind) + "-" + match[3]
+		if match[4] != "" {
+			id += "-" + strings.ToUpper(match[4])
+		}
+	}
+	return LookupSPDX(id)
+}
+
+// spdxCC return Creative Commons deed URL for SPDX identifier.
+func spdxCC(id string) string {
+	if strings.HasPrefix(id, "CC0-") {
+		return "https://creativecommons.org/publicdomain/zero/" + id[4:] + "/"
+	}
+	var match = reCCSPDX.FindStringSubmatch(id)
+	if match == nil {
+		return ""
+	}
+	var link = "https://creativecommons.org/licenses/" +
+		strings.ToLower(match[1]) + "/" + match[2] + "/"
+	if match[3] != "" {
+		link += strings.ToLower(match[3]) + "/"
+	}
+	return link
+}
+
+var (
+	reCopyright = regexp.MustCompile(`(?i)(?:©|\(c\)|copyright|copr\.)\s*(?:©|\(c\))?\s*(?:(\d{4})(?:\s*[-–]\s*(\d{4}))?[\s,]*)?([^,;.]*)`)
+	reCCText    = regexp.MustCompile(`(?i)\bCC[\s-]?(BY(?:[\s-](?:NC|ND|SA))*)(?:[\s-]+(\d\.\d))?\b`)
+	reCC0Text   = regexp.MustCompile(`(?i)\bCC[\s-]?(?:0|zero)\b`)
+	reURLText   = regexp.MustCompile(`https?://\S+`)
+	reSPDXText  = regexp.MustCompile(`(?i)\b(?:licen[cs]ed\s+under(?:\s+the)?|licen[cs]e\s*:?|spdx(?:-license-identifier)?\s*:)\s*([A-Za-z0-9][A-Za-z0-9.+-]*[A-Za-z0-9+])`)
+)
+
+// ParseRights return structured rights parsed from the free text like
+// "© 2007 John Smith, CC BY-NC". The last year of years range is used.
+// Creative Commons licenses without version are treated as version 4.0.
+// Other SPDX identifiers are recognized after "license", "licensed under"
+// or "SPDX:" only, like "© 2020 Nokia, licensed under MIT".
+func ParseRights(text string) (r Rights) {
+	if text = strings.TrimSpace(text); text == "" {
+		return r
+	}
+
+	// license
+	for _, link := range reURLText.FindAllString(text, -1) {
+		link = strings.TrimRight(link, ".,;)")
+		if ccSPDX(link) != "" {
+			r.License = ccSPDX(link)
+			r.URL = link
+			text = strings.Replace(text, link, "", 1)
+			break
+		}
+	}
+	if r.License == "" {
+		if match := reCCText.FindStringSubmatch(text); match != nil {
+			var version = match[2]
+			if version == "" {
+				version = "4.0"
+			}
+			var kind = strings.ToUpper(strings.Join(strings.FieldsFunc(match[1],
+				func(r rune) bool { return r == ' ' || r == '-' }), "-"))
+			r.License = LookupSPDX("CC-" + kind + "-" + version)
+			text = strings.Replace(text, match[0], "", 1)
+		} else if match := reCC0Text.FindString(text); match != "" {
+			r.License = "CC0-1.0"
+			text = strings.Replace(text, match, "", 1)
+		} else if match := reSPDXText.FindStringSubmatch(text); match != nil {
+			if id := LookupSPDX(match[1]); id != "" {
+				r.License = id
+				text = strings.Replace(text, match[0], "", 1)
+			}
+		}
+	}
+
+	// copyright
+	if match := reCopyright.FindStringSubmatch(text); match != nil {
+		var year = match[1]
+		if match[2] != "" {
+			year = match[2]
+		}
+		r.Year, _ = strconv.Atoi(year)
+		r.Holder = strings.TrimSpace(match[3])
+		r.Holder = strings.TrimSpace(strings.TrimPrefix(r.Holder, "by "))
+		if strings.EqualFold(r.Holder, "all rights reserved") {
+			r.Holder = ""
+		}
+	}
+	return r
+}
+
+// validateRights check the license and copyright year.
+func validateRights(p Publication) (warnings []Warning) {
+	if p.License != "" && !isURL(p.License) && LookupSPDX(p.License) == "" {
+		warnings = append(warnings, Warning{
+			Field:   "license",
+			Message: fmt.Sprintf("unknown SPDX license identifier %q", p.License),
+		})
+	}
+	if p.LicenseURL != "" && !isURL(p.LicenseURL) {
+		warnings = append(warnings, Warning{
+			Field:   "license-url",
+			Message: fmt.Sprintf("bad license URL %q", p.LicenseURL),
+		})
+	}
+	if year := p.CopyrightYear; year != 0 && (year < 1000 || year > time.Now().Year()+1) {
+		warnings = append(warnings, Warning{
+			Field:   "copyright-year",
+			Message: fmt.Sprintf("bad copyright year %d", year),
+		})
+	}
+	return warnings
+}
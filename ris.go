@@ -0,0 +1,300 @@
+package metadata
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// risType is a RIS reference type with corresponding EndNote reference type.
+type risType struct {
+	label   string // publication type
+	endNote int
+	name    string // EndNote reference type name
+}
+
+// risTypes is a supported RIS reference types.
+var risTypes = map[string]risType{
+	"BOOK":   {"book", 6, "Book"},
+	"EBOOK":  {"ebook", 44, "Electronic Book"},
+	"EDBOOK": {"edited book", 28, "Edited Book"},
+	"CHAP":   {"chapter", 5, "Book Section"},
+	"JOUR":   {"article", 17, "Journal Article"},
+	"THES":   {"thesis", 32, "Thesis"},
+	"RPRT":   {"report", 27, "Report"},
+	"SOUND":  {"audiobook", 3, "Audiovisual Material"},
+	"GEN":    {"generic", 13, "Generic"},
+}
+
+// risType return RIS reference type code for publication type: RIS code or
+// type label. Books are default.
+func (p Publication) risType() string {
+	var t = strings.TrimSpace(p.Type)
+	if _, ok := risTypes[strings.ToUpper(t)]; ok {
+		return strings.ToUpper(t)
+	}
+	for code, rt := range risTypes {
+		if strings.EqualFold(rt.label, t) {
+			return code
+		}
+	}
+	return "BOOK"
+}
+
+// citeName return author name in "Last, First" form used by citations.
+func citeName(author Author) string {
+	if author.FileAs != "" {
+		return author.FileAs
+	}
+	var name = fb2Author(author)
+	if name.LastName == "" {
+		return author.Text
+	}
+	return strings.TrimSpace(name.LastName + ", " +
+		strings.TrimSpace(name.FirstName+" "+name.MiddleName))
+}
+
+// citeAuthor return author from citation name in "Last, First" form.
+func citeAuthor(name string, role string) Author {
+	var author = Author{Text: name}
+	if i := strings.Index(name, ","); i > 0 {
+		author.FileAs = name
+		author.Text = strings.TrimSpace(strings.TrimSpace(name[i+1:]) + " " + name[:i])
+	}
+	if role != "" {
+		author.Role = Strings{role}
+	}
+	return author
+}
+
+// citeAuthors return citation names by RIS tag: AU for creators, A2 for
+// editors, A4 for translators and A3 for other contributors.
+func (p Publication) citeAuthors() map[string][]string {
+	var result = make(map[string][]string)
+	for _, author := range p.Creator.Sorted() {
+		result["AU"] = append(result["AU"], citeName(author))
+	}
+	for _, author := range p.Contributor.Sorted() {
+		var tag = "A3"
+		for _, code := range author.Relators(p.Language) {
+			if code == "edt" {
+				tag = "A2"
+				break
+			}
+			if code == "trl" {
+				tag = "A4"
+				break
+			}
+		}
+		result[tag] = append(result[tag], citeName(author))
+	}
+	return result
+}
+
+// citeRoles is a RIS author tags to publication roles.
+var citeRoles = map[string]string{"AU": "", "A2": "edt", "A3": "ctb", "A4": "trl"}
+
+// citeTitle return the main title joined with subtitle.
+func (p Publication) citeTitle() string {
+	var title = p.Title.Main()
+	for _, t := range p.Title {
+		if t.Type == "subtitle" {
+			return title + ": " + t.Text
+		}
+	}
+	return title
+}
+
+// RIS return publication metadata as RIS record.
+func (p Publication) RIS() []byte {
+	var buf bytes.Buffer
+	add := func(tag, value string) {
+		if value = strings.Join(strings.Fields(value), " "); value != "" {
+			fmt.Fprintf(&buf, "%s  - %s\r\n", tag, value)
+		}
+	}
+
+	add("TY", p.risType())
+	var authors = p.citeAuthors()
+	for _, tag := range []string{"AU", "A2", "A3", "A4"} {
+		for _, name := range authors[tag] {
+			add(tag, name)
+		}
+	}
+	add("TI", p.citeTitle())
+	add("T2", p.BelongsToCollection)
+	add("VL", p.GroupPosition)
+	if year := reYear.FindString(string(p.Date)); year != "" {
+		add("PY", year)
+		var parts = strings.SplitN(string(p.Date), "-", 3)
+		for len(parts) < 3 {
+			parts = append(parts, "")
+		}
+		if len(parts[2]) > 2 {
+			parts[2] = parts[2][:2] // skip time
+		}
+		add("DA", strings.Join(parts, "/")+"/")
+	}
+	for _, id := range p.Identifier {
+		switch scheme := strings.ToUpper(id.Scheme); {
+		case strings.HasPrefix(scheme, "ISBN"):
+			add("SN", id.Text)
+		case scheme == "DOI":
+			add("DO", normalizeDOI(id.Text))
+		}
+	}
+	add("PB", p.Publisher)
+	add("LA", p.Language)
+	add("AB", p.DescriptionText())
+	for _, subject := range p.Subject {
+		add("KW", subject)
+	}
+	buf.WriteString("ER  - \r\n")
+	return buf.Bytes()
+}
+
+// WriteRIS write publications as RIS records.
+func WriteRIS(w io.Writer, pubs ...*Publication) error {
+	for _, pub := range pubs {
+		if _, err := w.Write(pub.RIS()); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var reRISTag = regexp.MustCompile(`^([A-Z][A-Z0-9])  -(?: (.*))?$`)
+
+// ParseRIS return publications metadata from RIS records.
+func ParseRIS(data []byte) ([]*Publication, error) {
+	var (
+		pubs    []*Publication
+		pub     *Publication
+		lastTag string
+		year    string
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var text = strings.TrimRight(scanner.Text(), " \r")
+		var match = reRISTag.FindStringSubmatch(text)
+		if match == nil {
+			if pub == nil || strings.TrimSpace(text) == "" {
+				continue
+			}
+			// continuation of the previous line
+			if lastTag == "AB" {
+				pub.Description += "\n" + strings.TrimSpace(text)
+			}
+			continue
+		}
+
+		var tag, value = match[1], strings.TrimSpace(match[2])
+		lastTag = tag
+		if tag == "TY" {
+			pub = new(Publication)
+			year = ""
+			if rt, ok := risTypes[value]; ok && value != "BOOK" {
+				pub.Type = rt.label
+			} else if !ok {
+				pub.Type = value
+			}
+			continue
+		}
+		if pub == nil {
+			return nil, fmt.Errorf("RIS line %d: %s tag before TY", line, tag)
+		}
+
+		switch tag {
+		case "ER":
+			if pub.Date == "" && year != "" {
+				pub.Date = Date(year)
+			}
+			pubs = append(pubs, pub)
+			pub = nil
+		case "AU", "A1", "A2", "A3", "A4", "ED":
+			if tag == "A1" {
+				tag = "AU"
+			} else if tag == "ED" {
+				tag = "A2"
+			}
+			var author = citeAuthor(value, citeRoles[tag])
+			if tag == "AU" {
+				pub.Creator = append(pub.Creator, author)
+			} else {
+				pub.Contributor = append(pub.Contributor, author)
+			}
+		case "TI", "T1", "BT":
+			if len(pub.Title) == 0 && value != "" {
+				pub.Title = Titles{{Type: "main", Text: value}}
+			}
+		case "T2", "T3":
+			if pub.BelongsToCollection == "" {
+				pub.BelongsToCollection = value
+			}
+		case "VL":
+			pub.GroupPosition = value
+		case "PY", "Y1":
+			year = reYear.FindString(value)
+		case "DA":
+			pub.Date = risDate(value)
+		case "SN":
+			if value != "" {
+				var scheme = "ISBN-13"
+				if len(strings.ReplaceAll(value, "-", "")) == 10 {
+					scheme = "ISBN-10"
+				}
+				pub.Identifier = append(pub.Identifier, Identifier{Scheme: scheme, Text: value})
+			}
+		case "DO":
+			if value != "" {
+				pub.Identifier = append(pub.Identifier,
+					Identifier{Scheme: "DOI", Text: normalizeDOI(value)})
+			}
+		case "PB":
+			pub.Publisher = value
+		case "LA":
+			pub.Language = value
+		case "AB", "N2":
+			if pub.Description == "" {
+				pub.Description = value
+			}
+		case "KW":
+			if value != "" {
+				pub.Subject = append(pub.Subject, value)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if pub != nil {
+		return nil, fmt.Errorf("RIS record without ER")
+	}
+	return pubs, nil
+}
+
+// risDate return publication date from RIS date in YYYY/MM/DD/other form.
+func risDate(value string) Date {
+	var parts []string
+	for _, part := range strings.SplitN(value, "/", 4) {
+		if part == "" || len(parts) == 3 {
+			break
+		}
+		parts = append(parts, part)
+	}
+	var date = strings.Join(parts, "-")
+	if checkDateFormat(date) != nil {
+		if year := reYear.FindString(value); year != "" {
+			return Date(year)
+		}
+		return ""
+	}
+	return Date(date)
+}
@@ -0,0 +1,267 @@
+package metadata
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// SchemeDetector return true if the identifier text belongs to the scheme.
+type SchemeDetector func(text string) bool
+
+type identifierScheme struct {
+	name   string
+	detect SchemeDetector
+}
+
+// customSchemes are registered by RegisterScheme and checked before the
+// built-in ones.
+var customSchemes []identifierScheme
+
+// RegisterScheme add the identifier scheme detector, for example for the
+// publisher proprietary identifiers. Custom schemes are checked in the
+// order of registration before the built-in ones. It should be called on
+// initialization only.
+func RegisterScheme(name string, detect SchemeDetector) {
+	customSchemes = append(customSchemes, identifierScheme{name: name, detect: detect})
+}
+
+// builtinSchemes is the list of known identifier schemes in order of
+// detection.
+var builtinSchemes = []identifierScheme{
+	{"UUID", isUUID},
+	{"ISMN-13", isISMN13},
+	{"ISMN-10", isISMN10},
+	{"ISBN-13", isISBN13},
+	{"ISBN-10", isISBN10},
+	{"ISSN", isISSN},
+	{"DOI", isDOI},
+	{"ARK", isARK},
+	{"Handle", isHandle},
+	{"LCCN", isLCCN},
+	{"OCLC", isOCLC},
+	{"URN", isURN},
+	{"URL", isURL},
+}
+
+// DetectScheme return the scheme name of identifier text. Return error if
+// the text is empty or the scheme is not recognized.
+func DetectScheme(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", fmt.Errorf("empty identifier")
+	}
+	for _, list := range [][]identifierScheme{customSchemes, builtinSchemes} {
+		for _, scheme := range list {
+			if scheme.detect(text) {
+				return scheme.name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("unknown identifier scheme: %q", text)
+}
+
+// detectScheme set the identifier scheme by its text if not defined.
+// Unknown schemes are left empty.
+func (id *Identifier) detectScheme() {
+	if id.Scheme != "" {
+		return
+	}
+	id.Scheme, _ = DetectScheme(id.Text)
+}
+
+// trimPrefixFold return s without prefix in any case and true if the
+// prefix was found.
+func trimPrefixFold(s string, prefixes ...string) (string, bool) {
+	for _, prefix := range prefixes {
+		if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+			return s[len(prefix):], true
+		}
+	}
+	return s, false
+}
+
+var reUUID = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func isUUID(text string) bool {
+	text, _ = trimPrefixFold(text, "urn:uuid:")
+	if strings.HasPrefix(text, "{") && strings.HasSuffix(text, "}") {
+		text = text[1 : len(text)-1]
+	}
+	return reUUID.MatchString(text)
+}
+
+// standardNumber return digits of ISBN, ISMN or ISSN without prefix,
+// hyphens and spaces. Prefixed is true if one of prefixes was found.
+func standardNumber(text string, prefixes ...string) (digits string, prefixed bool) {
+	text, prefixed = trimPrefixFold(text, prefixes...)
+	text = strings.TrimSpace(text)
+	for _, r := range text {
+		switch {
+		case r >= '0' && r <= '9', r == 'X', r == 'x', r == 'M', r == 'm':
+			digits += strings.ToUpper(string(r))
+		case r == '-' || r == ' ':
+		default:
+			return "", prefixed
+		}
+	}
+	return digits, prefixed
+}
+
+// checkEAN13 return true if the EAN-13 check digit is valid.
+func checkEAN13(digits string) bool {
+	if len(digits) != 13 {
+		return false
+	}
+	var sum int
+	for i, r := range digits {
+		if r < '0' || r > '9' {
+			return false
+		}
+		sum += int(r-'0') * (1 + 2*(i%2))
+	}
+	return sum%10 == 0
+}
+
+// checkISBN10 return true if the ISBN-10 check digit is valid.
+func checkISBN10(digits string) bool {
+	if len(digits) != 10 {
+		return false
+	}
+	var sum int
+	for i, r := range digits {
+		var value int
+		switch {
+		case r >= '0' && r <= '9':
+			value = int(r - '0')
+		case r == 'X' && i == 9:
+			value = 10
+		default:
+			return false
+		}
+		sum += value * (10 - i)
+	}
+	return sum%11 == 0
+}
+
+var isbnPrefixes = []string{"urn:isbn:", "isbn:", "isbn "}
+
+// isISBN13 detect ISBN-13. The check digit is not tested when the text has
+// ISBN prefix.
+func isISBN13(text string) bool {
+	digits, prefixed := standardNumber(text, isbnPrefixes...)
+	if len(digits) != 13 || !(strings.HasPrefix(digits, "978") || strings.HasPrefix(digits, "979")) {
+		return false
+	}
+	return prefixed || checkEAN13(digits)
+}
+
+// isISBN10 detect ISBN-10. The check digit is not tested when the text has
+// ISBN prefix.
+func isISBN10(text string) bool {
+	digits, prefixed := standardNumber(text, isbnPrefixes...)
+	if len(digits) != 10 || strings.ContainsRune(digits[:9], 'X') {
+		return false
+	}
+	return prefixed || checkISBN10(digits)
+}
+
+var ismnPrefixes = []string{"urn:ismn:", "ismn:", "ismn "}
+
+func isISMN13(text string) bool {
+	digits, prefixed := standardNumber(text, ismnPrefixes...)
+	return len(digits) == 13 && strings.HasPrefix(digits, "9790") &&
+		(prefixed || checkEAN13(digits))
+}
+
+func isISMN10(text string) bool {
+	digits, prefixed := standardNumber(text, ismnPrefixes...)
+	return len(digits) == 10 && digits[0] == 'M' &&
+		(prefixed || checkEAN13("979"+"0"+digits[1:]))
+}
+
+var reISSN = regexp.MustCompile(`^\d{4}-\d{3}[\dXx]$`)
+
+// isISSN detect ISSN with hyphen or with ISSN prefix.
+func isISSN(text string) bool {
+	text, prefixed := trimPrefixFold(text, "urn:issn:", "issn:", "issn ")
+	text = strings.TrimSpace(text)
+	if !reISSN.MatchString(text) {
+		if !prefixed || len(text) != 8 {
+			return false
+		}
+		text = text[:4] + "-" + text[4:]
+		if !reISSN.MatchString(text) {
+			return false
+		}
+	}
+	var sum int
+	for i, r := range strings.ReplaceAll(strings.ToUpper(text), "-", "") {
+		var value = int(r - '0')
+		if r == 'X' {
+			value = 10
+		}
+		sum += value * (8 - i)
+	}
+	return sum%11 == 0
+}
+
+func isDOI(text string) bool {
+	return reDOI.MatchString(normalizeDOI(text))
+}
+
+var reARK = regexp.MustCompile(`(?i)^(?:https?://[^/]+/)?ark:/?\d{5,}/\S+$`)
+
+func isARK(text string) bool {
+	return reARK.MatchString(text)
+}
+
+var reHandle = regexp.MustCompile(`^\d+(\.\d+)*/\S+$`)
+
+// isHandle detect Handle in hdl: or resolver URL form.
+func isHandle(text string) bool {
+	text, ok := trimPrefixFold(text, "hdl:", "https://hdl.handle.net/", "http://hdl.handle.net/")
+	return ok && reHandle.MatchString(text)
+}
+
+var (
+	reLCCN       = regexp.MustCompile(`^[a-z]{0,3}\s?(\d{2}|\d{4})-?\d{1,6}$`)
+	reLCCNHyphen = regexp.MustCompile(`^[a-z]{0,3}\d{2}(\d{2})?-\d{6}$`)
+)
+
+// isLCCN detect LCCN with lccn: or permalink prefix, or in the hyphenated
+// form with six digits serial number like n78-890351.
+func isLCCN(text string) bool {
+	text, prefixed := trimPrefixFold(text, "lccn:", "info:lccn/",
+		"https://lccn.loc.gov/", "http://lccn.loc.gov/")
+	if prefixed {
+		return reLCCN.MatchString(strings.TrimSpace(text))
+	}
+	return reLCCNHyphen.MatchString(text)
+}
+
+var reOCLC = regexp.MustCompile(`^(?:\(OCoLC\)\s*|oclc:\s*|ocm|ocn|on)\d+$`)
+
+// isOCLC detect OCLC number with (OCoLC), oclc:, ocm, ocn or on prefix.
+func isOCLC(text string) bool {
+	return reOCLC.MatchString(text)
+}
+
+var reURN = regexp.MustCompile(`(?i)^urn:[a-z0-9][a-z0-9-]{0,31}:\S+$`)
+
+func isURN(text string) bool {
+	return reURN.MatchString(text)
+}
+
+func isURL(text string) bool {
+	u, err := url.Parse(text)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "ftp":
+		return true
+	}
+	return false
+}
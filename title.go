@@ -13,6 +13,7 @@ type Title struct {
 	Type   string `yaml:",omitempty"`
 	Text   string `yaml:"text"`
 	FileAs string `yaml:"file-as,omitempty"`
+	Lang   string `yaml:"lang,omitempty"`
 }
 
 // UnmarshalYAML implement yaml.Unmarshaler interface.
@@ -47,7 +48,8 @@ func (titles Titles) MarshalYAML() (interface{}, error) {
 		}, nil
 	case 1:
 		var title = titles[0]
-		if (title.Type == "" || title.Type == "main") && title.FileAs == "" {
+		if (title.Type == "" || title.Type == "main") && title.FileAs == "" &&
+			title.Lang == "" {
 			return title.Text, nil
 		}
 		return title, nil
@@ -0,0 +1,141 @@
+package metadata
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// NamespaceURL is the RFC 4122 name space for URLs.
+const NamespaceURL = "6ba7b811-9dad-11d1-80b4-00c04fd430c8"
+
+// UUIDNamespace is the name space used to derive publication UUIDs. The
+// default one is derived from the package URL; publishers may define their
+// own to get UUIDs unique for them.
+var UUIDNamespace = mustUUIDv5(NamespaceURL, "https://github.com/mdigger/metadata")
+
+// parseUUID return UUID bytes. The value may be in urn:uuid: form or in
+// braces. Only RFC 4122 variant and versions 1-5 are valid.
+func parseUUID(s string) (uuid [16]byte, err error) {
+	var text = strings.TrimPrefix(strings.TrimSpace(s), "urn:uuid:")
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "{"), "}")
+	if len(text) != 36 || text[8] != '-' || text[13] != '-' || text[18] != '-' || text[23] != '-' {
+		return uuid, fmt.Errorf("bad UUID format %q", s)
+	}
+	if _, err := hex.Decode(uuid[:], []byte(strings.ReplaceAll(text, "-", ""))); err != nil {
+		return uuid, fmt.Errorf("bad UUID format %q", s)
+	}
+	if version := uuid[6] >> 4; version < 1 || version > 5 {
+		return uuid, fmt.Errorf("bad UUID version %d: %q", version, s)
+	}
+	if uuid[8]&0xc0 != 0x80 {
+		return uuid, fmt.Errorf("bad UUID variant: %q", s)
+	}
+	return uuid, nil
+}
+
+// formatUUID return UUID in canonical string form.
+func formatUUID(uuid [16]byte) string {
+	var s = hex.EncodeToString(uuid[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+// NewUUIDv5 return name based UUID version 5 (SHA-1) in the namespace.
+func NewUUIDv5(namespace, name string) (string, error) {
+	ns, err := parseUUID(namespace)
+	if err != nil {
+		return "", fmt.Errorf("bad namespace: %w", err)
+	}
+	h := sha1.New()
+	h.Write(ns[:])
+	h.Write([]byte(name))
+	var uuid [16]byte
+	copy(uuid[:], h.Sum(nil))
+	uuid[6] = uuid[6]&0x0f | 0x50 // version 5
+	uuid[8] = uuid[8]&0x3f | 0x80 // RFC 4122 variant
+	return formatUUID(uuid), nil
+}
+
+func mustUUIDv5(namespace, name string) string {
+	uuid, err := NewUUIDv5(namespace, name)
+	if err != nil {
+		panic(err)
+	}
+	return uuid
+}
+
+// isbn13 return ISBN digits in ISBN-13 form or empty string if isbn is not
+// valid ISBN-10 or ISBN-13.
+func isbn13(isbn string) string {
+	const numbers = "0123456789"
+	var digits, _ = standardNumber(isbn, isbnPrefixes...)
+	switch {
+	case len(digits) == 10 && strings.Trim(digits[:9], numbers) == "" &&
+		strings.ContainsAny(digits[9:], numbers+"X"):
+		digits = "978" + digits[:9]
+		var sum int
+		for i, r := range digits {
+			sum += int(r-'0') * (1 + 2*(i%2))
+		}
+		return digits + string(rune('0'+(10-sum%10)%10))
+	case len(digits) == 13 && strings.Trim(digits, numbers) == "":
+		return digits
+	}
+	return ""
+}
+
+// UUID return publication UUID version 5 derived from ISBN or, when no
+// ISBN is defined, from the title, creators and publisher. Not valid ISBN
+// text is used as is. The same metadata always give the same UUID. Empty
+// namespace means UUIDNamespace.
+func (p Publication) UUID(namespace string) (string, error) {
+	if namespace == "" {
+		namespace = UUIDNamespace
+	}
+
+	for _, id := range p.Identifier {
+		if !strings.HasPrefix(strings.ToUpper(id.Scheme), "ISBN") &&
+			!strings.HasPrefix(id.Text, "urn:isbn:") {
+			continue
+		}
+		if isbn := isbn13(id.Text); isbn != "" {
+			return NewUUIDv5(namespace, "urn:isbn:"+isbn)
+		}
+		return NewUUIDv5(namespace, strings.TrimSpace(id.Text))
+	}
+
+	var title = p.Title.Main()
+	if title == "" && len(p.Title) > 0 {
+		title = p.Title[0].Text
+	}
+	if title == "" {
+		return "", fmt.Errorf("title or ISBN is required for UUID")
+	}
+	normalize := func(s string) string {
+		return strings.ToLower(strings.Join(strings.Fields(s), " "))
+	}
+	var names = p.creatorNames()
+	for i, name := range names {
+		names[i] = normalize(name)
+	}
+	var name = strings.Join([]string{
+		normalize(title), strings.Join(names, ";"), normalize(p.Publisher)}, "\n")
+	return NewUUIDv5(namespace, name)
+}
+
+// validateUUID check the UUID identifiers version and variant.
+func validateUUID(ids Identifiers) (warnings []Warning) {
+	for i, id := range ids {
+		if !strings.EqualFold(id.Scheme, "UUID") {
+			continue
+		}
+		if _, err := parseUUID(id.Text); err != nil {
+			warnings = append(warnings, Warning{
+				Field:   fmt.Sprintf("identifier[%d]", i),
+				Message: err.Error(),
+			})
+		}
+	}
+	return warnings
+}
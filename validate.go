@@ -0,0 +1,119 @@
+package metadata
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Warning describes a non-fatal problem found in publication metadata.
+type Warning struct {
+	Field   string // metadata field name
+	Message string
+}
+
+// Error implement error interface.
+func (w Warning) Error() string {
+	return fmt.Sprintf("%s: %s", w.Field, w.Message)
+}
+
+// Validate check publication metadata and return the list of found problems.
+// The referenced files are checked for publication loaded from file only.
+func (p Publication) Validate() (warnings []Warning) {
+	warnings = append(warnings, validatePrimary(p.Identifier)...)
+	warnings = append(warnings, validateRoles("creator", p.Creator, p.Language)...)
+	warnings = append(warnings, validateRoles("contributor", p.Contributor, p.Language)...)
+	warnings = append(warnings, validateDuration(p)...)
+	warnings = append(warnings, validateModified(p.Modified)...)
+	warnings = append(warnings, validateDOI(p.Identifier)...)
+	warnings = append(warnings, validateUUID(p.Identifier)...)
+	warnings = append(warnings, validateRights(p)...)
+	warnings = append(warnings, validatePrefixes(p)...)
+	warnings = append(warnings, validateVendors(p)...)
+	warnings = append(warnings, validateCover(p.CoverImage)...)
+	if p.dir != "" {
+		warnings = append(warnings, p.ValidateFiles(p.dir)...)
+	}
+	return warnings
+}
+
+// ValidateFiles check the files referenced by publication, like the cover
+// image, with relative paths resolved from dir.
+func (p Publication) ValidateFiles(dir string) []Warning {
+	return validateCoverFiles(p.CoverImage, dir)
+}
+
+// validatePrimary check that no more than one identifier is marked as
+// primary. Without marks the primary one is selected by Identifiers.Primary.
+func validatePrimary(ids Identifiers) (warnings []Warning) {
+	var primary []string
+	for i, id := range ids {
+		if id.Primary {
+			primary = append(primary, fmt.Sprintf("%d", i))
+		}
+	}
+	if len(primary) > 1 {
+		warnings = append(warnings, Warning{
+			Field: "identifier",
+			Message: fmt.Sprintf("more than one primary identifier: %s",
+				strings.Join(primary, ", ")),
+		})
+	}
+	return warnings
+}
+
+// validateModified check that the modification date can be used as EPUB
+// dcterms:modified value.
+func validateModified(modified Date) (warnings []Warning) {
+	if _, ok := modified.EPUBModified(); modified != "" && !ok {
+		warnings = append(warnings, Warning{
+			Field:   "modified",
+			Message: fmt.Sprintf("date %q has no day and is not used as EPUB modification date", modified),
+		})
+	}
+	return warnings
+}
+
+// validateRoles check that all authors roles are known relators.
+func validateRoles(field string, authors Authors, lang string) (warnings []Warning) {
+	for i, author := range authors {
+		for _, role := range author.Role {
+			if _, ok := LookupRelator(role, lang); ok || comicInfoLabel(role) {
+				continue
+			}
+			msg := fmt.Sprintf("unknown role %q of %q", role, author.Text)
+			if suggest := SuggestRelators(role, lang); len(suggest) > 0 {
+				msg += fmt.Sprintf(", did you mean %q?",
+					strings.Join(suggest, `" or "`))
+			}
+			warnings = append(warnings, Warning{
+				Field:   fmt.Sprintf("%s[%d].role", field, i),
+				Message: msg,
+			})
+		}
+	}
+	return warnings
+}
+
+// validateDuration check that the total duration of audiobook matches the
+// tracks durations.
+func validateDuration(p Publication) (warnings []Warning) {
+	if p.Duration == "" || len(p.ReadingOrder) == 0 {
+		return nil
+	}
+	var total time.Duration
+	for _, track := range p.ReadingOrder {
+		if track.Duration == "" {
+			return nil // unknown
+		}
+		total += track.Duration.Duration()
+	}
+	if diff := total - p.Duration.Duration(); diff > time.Second || diff < -time.Second {
+		warnings = append(warnings, Warning{
+			Field: "duration",
+			Message: fmt.Sprintf("total duration %s does not match the reading order %s",
+				p.Duration, NewDuration(total)),
+		})
+	}
+	return warnings
+}
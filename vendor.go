@@ -0,0 +1,173 @@
+package metadata
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	epub "github.com/mdigger/epub3"
+)
+
+// Apple is an Apple Books specific metadata.
+type Apple struct {
+	Version               Version `yaml:"version,omitempty"`
+	SpecifiedFonts        bool    `yaml:"specified-fonts,omitempty"`
+	IPadOrientationLock   string  `yaml:"ipad-orientation-lock,omitempty"`   // portrait-only, landscape-only or none
+	IPhoneOrientationLock string  `yaml:"iphone-orientation-lock,omitempty"` // portrait-only, landscape-only or none
+	Binding               *bool   `yaml:"binding,omitempty"`                 // show binding for fixed layout
+	ScrollAxis            string  `yaml:"scroll-axis,omitempty"`             // vertical, horizontal or default
+}
+
+// epub return ibooks metas.
+func (a Apple) epub() (metas []epub.Meta) {
+	add := func(name, value string) {
+		if value != "" {
+			metas = append(metas, epub.Meta{Property: "ibooks:" + name, Value: value})
+		}
+	}
+	add("version", string(a.Version))
+	if a.SpecifiedFonts {
+		add("specified-fonts", "true") // not "yes"
+	}
+	add("ipad-orientation-lock", a.IPadOrientationLock)
+	add("iphone-orientation-lock", a.IPhoneOrientationLock)
+	if a.Binding != nil {
+		add("binding", strconv.FormatBool(*a.Binding))
+	}
+	add("scroll-axis", a.ScrollAxis)
+	return metas
+}
+
+// validate check the Apple Books options values.
+func (a Apple) validate() (warnings []Warning) {
+	warnings = append(warnings, checkOption("ibooks.ipad-orientation-lock",
+		a.IPadOrientationLock, "portrait-only", "landscape-only", "none")...)
+	warnings = append(warnings, checkOption("ibooks.iphone-orientation-lock",
+		a.IPhoneOrientationLock, "portrait-only", "landscape-only", "none")...)
+	warnings = append(warnings, checkOption("ibooks.scroll-axis",
+		a.ScrollAxis, "vertical", "horizontal", "default")...)
+	return warnings
+}
+
+// Kobo is a Kobo specific metadata. Kobo has no own metadata vocabulary
+// and reads the fixed layout options as EPUB 3 rendition properties, so
+// no kobo: metas are written.
+type Kobo struct {
+	FixedLayout bool   `yaml:"fixed-layout,omitempty"` // pre-paginated layout
+	Orientation string `yaml:"orientation,omitempty"`  // auto, landscape or portrait
+	Spread      string `yaml:"spread,omitempty"`       // auto, none, landscape or both
+}
+
+// epub return rendition metas. Layout is not added if it is already
+// defined by the publication.
+func (k Kobo) epub(layout string) (metas []epub.Meta) {
+	if k.FixedLayout && layout == "" {
+		metas = append(metas, epub.Meta{Property: "rendition:layout", Value: "pre-paginated"})
+	}
+	if k.Orientation != "" {
+		metas = append(metas, epub.Meta{Property: "rendition:orientation", Value: k.Orientation})
+	}
+	if k.Spread != "" {
+		metas = append(metas, epub.Meta{Property: "rendition:spread", Value: k.Spread})
+	}
+	return metas
+}
+
+// validate check the Kobo options values.
+func (k Kobo) validate() (warnings []Warning) {
+	warnings = append(warnings, checkOption("kobo.orientation",
+		k.Orientation, "auto", "landscape", "portrait")...)
+	warnings = append(warnings, checkOption("kobo.spread",
+		k.Spread, "auto", "none", "landscape", "both")...)
+	return warnings
+}
+
+// Kindle is an Amazon Kindle specific metadata. Kindle reads it from EPUB 2
+// name and content metas, so it's written by EPUB2 and EPUBHybrid; EPUB
+// gives the fixed layout as rendition property only.
+type Kindle struct {
+	PrimaryWritingMode  string `yaml:"primary-writing-mode,omitempty"` // horizontal-lr, horizontal-rl, vertical-lr or vertical-rl
+	FixedLayout         bool   `yaml:"fixed-layout,omitempty"`
+	OriginalResolution  string `yaml:"original-resolution,omitempty"` // like 1024x600
+	BookType            string `yaml:"book-type,omitempty"`           // comic or children
+	RegionMagnification bool   `yaml:"region-magnification,omitempty"`
+}
+
+// opf return Kindle metas in EPUB 2 form.
+func (k Kindle) opf() (metas []OPFMeta) {
+	add := func(name, value string) {
+		if value != "" {
+			metas = append(metas, OPFMeta{Name: name, Content: value})
+		}
+	}
+	add("primary-writing-mode", k.PrimaryWritingMode)
+	if k.FixedLayout {
+		add("fixed-layout", "true")
+	}
+	add("original-resolution", k.OriginalResolution)
+	add("book-type", k.BookType)
+	if k.RegionMagnification {
+		add("RegionMagnification", "true")
+	}
+	return metas
+}
+
+// epub return rendition layout for the fixed layout.
+func (k Kindle) epub(layout string) (metas []epub.Meta) {
+	if k.FixedLayout && layout == "" {
+		metas = append(metas, epub.Meta{Property: "rendition:layout", Value: "pre-paginated"})
+	}
+	return metas
+}
+
+var reResolution = regexp.MustCompile(`^[1-9]\d*x[1-9]\d*$`)
+
+// validate check the Kindle options values.
+func (k Kindle) validate() (warnings []Warning) {
+	warnings = append(warnings, checkOption("kindle.primary-writing-mode", k.PrimaryWritingMode,
+		"horizontal-lr", "horizontal-rl", "vertical-lr", "vertical-rl")...)
+	warnings = append(warnings, checkOption("kindle.book-type", k.BookType, "comic", "children")...)
+	if k.OriginalResolution != "" && !reResolution.MatchString(k.OriginalResolution) {
+		warnings = append(warnings, Warning{
+			Field:   "kindle.original-resolution",
+			Message: fmt.Sprintf("bad resolution %q, WIDTHxHEIGHT expected", k.OriginalResolution),
+		})
+	}
+	if k.RegionMagnification && !k.FixedLayout {
+		warnings = append(warnings, Warning{
+			Field:   "kindle.region-magnification",
+			Message: "region magnification is used with fixed layout only",
+		})
+	}
+	return warnings
+}
+
+// checkOption return warning if not empty value is not one of the allowed.
+func checkOption(field, value string, allowed ...string) []Warning {
+	if value == "" {
+		return nil
+	}
+	for _, v := range allowed {
+		if value == v {
+			return nil
+		}
+	}
+	return []Warning{{
+		Field:   field,
+		Message: fmt.Sprintf("unsupported value %q, expected one of %v", value, allowed),
+	}}
+}
+
+// validateVendors check vendor specific metadata.
+func validateVendors(p Publication) (warnings []Warning) {
+	if p.IBooks != nil {
+		warnings = append(warnings, p.IBooks.validate()...)
+	}
+	if p.Kobo != nil {
+		warnings = append(warnings, p.Kobo.validate()...)
+	}
+	if p.Kindle != nil {
+		warnings = append(warnings, p.Kindle.validate()...)
+	}
+	return warnings
+}
@@ -0,0 +1,354 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// XMP namespaces.
+const (
+	xmpNSRDF    = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmpNSDC     = "http://purl.org/dc/elements/1.1/"
+	xmpNSXMP    = "http://ns.adobe.com/xap/1.0/"
+	xmpNSPDF    = "http://ns.adobe.com/pdf/1.3/"
+	xmpNSPDFX   = "http://ns.adobe.com/pdfx/1.3/"
+	xmpNSPRISM  = "http://prismstandard.org/namespaces/basic/2.0/"
+	xmpNSPDFAID = "http://www.aiim.org/pdfa/ns/id/"
+)
+
+// XMPOptions defines optional properties of XMP packet.
+type XMPOptions struct {
+	CreatorTool     string // xmp:CreatorTool
+	Producer        string // pdf:Producer
+	PDFAPart        int    // PDF/A part: 1, 2 or 3; not defined if zero
+	PDFAConformance string // PDF/A conformance level: A, B or U
+	NoProperties    bool   // do not write Properties as pdfx custom fields
+}
+
+var reXMLName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// XMP return publication metadata as XMP packet for PDF. Options may be
+// nil.
+//
+// Properties with simple values and names valid as XML names are written
+// as pdfx custom fields. Note that PDF/A requires an extension schema for
+// custom fields, so use NoProperties option for PDF/A if needed.
+func (p Publication) XMP(opts *XMPOptions) []byte {
+	if opts == nil {
+		opts = new(XMPOptions)
+	}
+
+	var b bytes.Buffer
+	text := func(s string) string {
+		var buf bytes.Buffer
+		xml.EscapeText(&buf, []byte(s))
+		return buf.String()
+	}
+	simple := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "   <%s>%s</%[1]s>\n", name, text(value))
+		}
+	}
+	array := func(name, kind string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "   <%s>\n    <rdf:%s>\n", name, kind)
+		for _, value := range values {
+			fmt.Fprintf(&b, "     <rdf:li>%s</rdf:li>\n", text(value))
+		}
+		fmt.Fprintf(&b, "    </rdf:%s>\n   </%s>\n", kind, name)
+	}
+	alt := func(name string, values [][2]string) {
+		if len(values) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "   <%s>\n    <rdf:Alt>\n", name)
+		for _, value := range values {
+			fmt.Fprintf(&b, "     <rdf:li xml:lang=\"%s\">%s</rdf:li>\n",
+				text(value[0]), text(value[1]))
+		}
+		fmt.Fprintf(&b, "    </rdf:Alt>\n   </%s>\n", name)
+	}
+
+	b.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	fmt.Fprintf(&b, " <rdf:RDF xmlns:rdf=%q>\n", xmpNSRDF)
+	fmt.Fprintf(&b, "  <rdf:Description rdf:about=\"\"\n"+
+		"    xmlns:dc=%q\n    xmlns:xmp=%q\n    xmlns:pdf=%q\n"+
+		"    xmlns:pdfx=%q\n    xmlns:prism=%q\n    xmlns:pdfaid=%q>\n",
+		xmpNSDC, xmpNSXMP, xmpNSPDF, xmpNSPDFX, xmpNSPRISM, xmpNSPDFAID)
+
+	simple("dc:format", "application/pdf")
+
+	// titles: the main title of publication language is the default
+	var titles [][2]string
+	for _, title := range p.Title {
+		if title.Type != "main" && title.Type != "" {
+			continue
+		}
+		var lang = title.Lang
+		if len(titles) == 0 {
+			lang = "x-default"
+		} else if lang == "" {
+			continue
+		}
+		titles = append(titles, [2]string{lang, title.Text})
+	}
+	alt("dc:title", titles)
+
+	var creators []string
+	for _, author := range p.Creator.Sorted() {
+		creators = append(creators, author.Text)
+	}
+	array("dc:creator", "Seq", creators)
+
+	if description := p.DescriptionText(); description != "" {
+		alt("dc:description", [][2]string{{"x-default", description}})
+	}
+	array("dc:subject", "Bag", p.Subject)
+	if rights := p.RightsText(); rights != "" {
+		alt("dc:rights", [][2]string{{"x-default", rights}})
+	}
+	if p.Publisher != "" {
+		array("dc:publisher", "Bag", []string{p.Publisher})
+	}
+	if p.Language != "" {
+		array("dc:language", "Bag", []string{p.Language})
+	}
+
+	// identifiers
+	if id, ok := p.Identifier.primary(); ok {
+		simple("dc:identifier", id.URN())
+	}
+	for _, id := range p.Identifier {
+		switch scheme := strings.ToUpper(id.Scheme); {
+		case strings.HasPrefix(scheme, "ISBN"):
+			simple("prism:isbn", strings.TrimPrefix(id.Text, "urn:isbn:"))
+		case scheme == "DOI":
+			simple("prism:doi", strings.TrimPrefix(id.Text, "doi:"))
+		}
+	}
+
+	// dates & tools
+	simple("xmp:CreateDate", string(p.Date))
+	simple("xmp:ModifyDate", string(p.Modified))
+	simple("xmp:CreatorTool", opts.CreatorTool)
+	simple("pdf:Producer", opts.Producer)
+	simple("pdf:Keywords", strings.Join(p.Subject, ", "))
+
+	// custom fields
+	if !opts.NoProperties {
+		var names = make([]string, 0, len(p.Properties))
+		for name := range p.Properties {
+			if reXMLName.MatchString(name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			switch value := p.Properties[name].(type) {
+			case string, int, int64, float64, bool:
+				simple("pdfx:"+name, fmt.Sprint(value))
+			}
+		}
+	}
+
+	// PDF/A identification
+	if opts.PDFAPart > 0 {
+		simple("pdfaid:part", fmt.Sprint(opts.PDFAPart))
+		simple("pdfaid:conformance", strings.ToUpper(opts.PDFAConformance))
+	}
+
+	b.WriteString("  </rdf:Description>\n </rdf:RDF>\n</x:xmpmeta>\n")
+	b.WriteString(strings.Repeat(strings.Repeat(" ", 99)+"\n", 20)) // padding
+	b.WriteString("<?xpacket end=\"w\"?>")
+	return b.Bytes()
+}
+
+// xmpRDF is an XMP RDF structure for reading.
+type xmpRDF struct {
+	Descriptions []xmpDescription `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# Description"`
+}
+
+type xmpDescription struct {
+	Title       xmpArray    `xml:"http://purl.org/dc/elements/1.1/ title"`
+	Creator     xmpArray    `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Description xmpArray    `xml:"http://purl.org/dc/elements/1.1/ description"`
+	Subject     xmpArray    `xml:"http://purl.org/dc/elements/1.1/ subject"`
+	Rights      xmpArray    `xml:"http://purl.org/dc/elements/1.1/ rights"`
+	Publisher   xmpArray    `xml:"http://purl.org/dc/elements/1.1/ publisher"`
+	Language    xmpArray    `xml:"http://purl.org/dc/elements/1.1/ language"`
+	Identifier  string      `xml:"http://purl.org/dc/elements/1.1/ identifier"`
+	CreateDate  string      `xml:"http://ns.adobe.com/xap/1.0/ CreateDate"`
+	ModifyDate  string      `xml:"http://ns.adobe.com/xap/1.0/ ModifyDate"`
+	ISBN        string      `xml:"isbn"` // any prism version
+	DOI         string      `xml:"doi"`
+	Attrs       []xml.Attr  `xml:",any,attr"`
+	Other       []xmpSimple `xml:",any"`
+}
+
+// xmpArray is an XMP array (Alt, Seq or Bag) or a simple value.
+type xmpArray struct {
+	Alt  []xmpItem `xml:"Alt>li"`
+	Seq  []xmpItem `xml:"Seq>li"`
+	Bag  []xmpItem `xml:"Bag>li"`
+	Text string    `xml:",chardata"`
+}
+
+type xmpItem struct {
+	Lang  string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmpSimple struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+// items return all array items or simple value as single item.
+func (a xmpArray) items() []xmpItem {
+	var items = append(append(append([]xmpItem(nil), a.Alt...), a.Seq...), a.Bag...)
+	if len(items) == 0 {
+		if text := strings.TrimSpace(a.Text); text != "" {
+			items = []xmpItem{{Value: text}}
+		}
+	}
+	return items
+}
+
+// values return all non empty array values.
+func (a xmpArray) values() []string {
+	var values []string
+	for _, item := range a.items() {
+		if value := strings.TrimSpace(item.Value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// ParseXMP return publication metadata from the first XMP packet found in
+// data. The data may be the whole PDF file.
+func ParseXMP(data []byte) (*Publication, error) {
+	start := bytes.Index(data, []byte("<x:xmpmeta"))
+	if start < 0 {
+		start = bytes.Index(data, []byte("<rdf:RDF"))
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("XMP packet not found")
+	}
+	data = data[start:]
+	if end := bytes.Index(data, []byte("<?xpacket end")); end > 0 {
+		data = data[:end]
+	}
+
+	var rdf xmpRDF
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("bad XMP packet: %v", err)
+		}
+		if se, ok := token.(xml.StartElement); ok && se.Name.Space == xmpNSRDF &&
+			se.Name.Local == "RDF" {
+			if err := dec.DecodeElement(&rdf, &se); err != nil {
+				return nil, fmt.Errorf("bad XMP packet: %v", err)
+			}
+			break
+		}
+	}
+
+	var pub = new(Publication)
+	addID := func(id Identifier) {
+		for _, item := range pub.Identifier {
+			if item.URN() == id.URN() {
+				return
+			}
+		}
+		pub.Identifier = append(pub.Identifier, id)
+	}
+	for _, d := range rdf.Descriptions {
+		// simple properties as attributes
+		for _, attr := range d.Attrs {
+			switch {
+			case attr.Name.Space == xmpNSXMP && attr.Name.Local == "CreateDate":
+				d.CreateDate = attr.Value
+			case attr.Name.Space == xmpNSXMP && attr.Name.Local == "ModifyDate":
+				d.ModifyDate = attr.Value
+			case attr.Name.Space == xmpNSPDFX:
+				pub.setProperty(attr.Name.Local, attr.Value)
+			}
+		}
+
+		for _, item := range d.Title.items() {
+			var title = Title{Type: "main", Text: strings.TrimSpace(item.Value)}
+			if item.Lang != "x-default" {
+				title.Lang = item.Lang
+			}
+			pub.Title = append(pub.Title, title)
+		}
+		for _, name := range d.Creator.values() {
+			pub.Creator = append(pub.Creator, Author{Text: name})
+		}
+		if values := d.Description.values(); len(values) > 0 {
+			pub.Description = values[0]
+		}
+		pub.Subject = append(pub.Subject, d.Subject.values()...)
+		if values := d.Rights.values(); len(values) > 0 {
+			pub.Rights = values[0]
+		}
+		if values := d.Publisher.values(); len(values) > 0 {
+			pub.Publisher = values[0]
+		}
+		if values := d.Language.values(); len(values) > 0 {
+			pub.Language = values[0]
+		}
+
+		if text := strings.TrimSpace(d.Identifier); text != "" {
+			var id = Identifier{Text: text}
+			id.detectScheme()
+			addID(id)
+		}
+		if isbn := strings.TrimSpace(d.ISBN); isbn != "" {
+			addID(Identifier{Scheme: "ISBN-13", Text: isbn})
+		}
+		if doi := strings.TrimSpace(d.DOI); doi != "" {
+			addID(Identifier{Scheme: "DOI", Text: doi})
+		}
+
+		if date := xmpDate(d.CreateDate); date != "" {
+			pub.Date = date
+		}
+		if date := xmpDate(d.ModifyDate); date != "" {
+			pub.Modified = date
+		}
+
+		for _, field := range d.Other {
+			if field.XMLName.Space == xmpNSPDFX {
+				pub.setProperty(field.XMLName.Local, strings.TrimSpace(field.Value))
+			}
+		}
+	}
+
+	return pub, nil
+}
+
+// xmpDate return publication date from XMP date.
+func xmpDate(value string) Date {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ""
+	}
+	if checkDateFormat(value) == nil {
+		return Date(value)
+	}
+	if len(value) > 10 && checkDateFormat(value[:10]) == nil {
+		return Date(value[:10])
+	}
+	return ""
+}